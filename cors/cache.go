@@ -0,0 +1,99 @@
+package cors
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// originCache is a size bounded cache of origin decisions. When the cache
+// is full the least recently used entry is evicted.
+type originCache struct {
+	mutex   *sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	origin  string
+	allowed bool
+	expires time.Time
+}
+
+func newOriginCache(ttl time.Duration, size int) *originCache {
+	return &originCache{
+		mutex:   &sync.Mutex{},
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+func (oc *originCache) get(origin string) (allowed, ok bool) {
+	oc.mutex.Lock()
+	defer oc.mutex.Unlock()
+
+	elem, found := oc.entries[origin]
+	if !found {
+		return false, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !oc.now().Before(entry.expires) {
+		oc.order.Remove(elem)
+		delete(oc.entries, origin)
+		return false, false
+	}
+
+	oc.order.MoveToFront(elem)
+	return entry.allowed, true
+}
+
+func (oc *originCache) set(origin string, allowed bool) {
+	oc.mutex.Lock()
+	defer oc.mutex.Unlock()
+
+	expires := oc.now().Add(oc.ttl)
+	if elem, found := oc.entries[origin]; found {
+		entry := elem.Value.(*cacheEntry)
+		entry.allowed = allowed
+		entry.expires = expires
+		oc.order.MoveToFront(elem)
+		return
+	}
+
+	oc.entries[origin] = oc.order.PushFront(&cacheEntry{
+		origin:  origin,
+		allowed: allowed,
+		expires: expires,
+	})
+
+	if oc.order.Len() > oc.size {
+		oldest := oc.order.Back()
+		oc.order.Remove(oldest)
+		delete(oc.entries, oldest.Value.(*cacheEntry).origin)
+	}
+}
+
+func (oc *originCache) remove(origin string) {
+	oc.mutex.Lock()
+	defer oc.mutex.Unlock()
+
+	if elem, found := oc.entries[origin]; found {
+		oc.order.Remove(elem)
+		delete(oc.entries, origin)
+	}
+}
+
+func (oc *originCache) clear() {
+	oc.mutex.Lock()
+	defer oc.mutex.Unlock()
+
+	oc.entries = make(map[string]*list.Element)
+	oc.order.Init()
+}
@@ -1,19 +1,44 @@
 package cors
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
+var (
+	// ErrCredentialsWithAnyOrigin is returned by New when credentials are
+	// allowed together with the "*" origin. Browsers refuse such responses
+	// and echoing any origin instead would expose the credentials of users
+	// to every site on the internet.
+	ErrCredentialsWithAnyOrigin = errors.New("cors: credentials can not be allowed for any origin (\"*\")")
+)
+
 // Cors holds the functions and data configured and provide the middleware
 // used for CORS (Cross-origin resource sharing).
 type Cors struct {
 	allowedOrigins []string
+	originPatterns []*regexp.Regexp
 	allowedHeaders []string
 	allowedMethods []string
+	exposedHeaders []string
 	maxAge         time.Duration
+	rejectStatus   int
+
+	allowCredentials    bool
+	allowPrivateNetwork bool
+
+	originResolver OriginResolverFunc
+	cache          *originCache
+	errorHandler   ErrorHandlerFunc
+
+	eventFunc EventFunc
+	rejected  map[Reason]int64
+	mutex     *sync.Mutex
 }
 
 // ConfigFunc is the type of function used to configure the Cors
@@ -22,49 +47,180 @@ type Cors struct {
 type ConfigFunc func(*Cors)
 
 // New creates a new Cors instance that is configured with the given
-// ConfigFunc.
+// ConfigFunc. New panics if the resulting configuration is insecure, use
+// NewChecked to get the error instead.
 func New(configs ...ConfigFunc) *Cors {
-	c := &Cors{}
+	c, err := NewChecked(configs...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// NewChecked creates a new Cors instance like New, but returns an error
+// instead of panicking if the resulting configuration is insecure.
+func NewChecked(configs ...ConfigFunc) (*Cors, error) {
+	c := &Cors{
+		rejectStatus: http.StatusForbidden,
+		errorHandler: defaultErrorHandler,
+		rejected:     make(map[Reason]int64),
+		mutex:        &sync.Mutex{},
+	}
 
 	for _, cFn := range configs {
 		cFn(c)
 	}
 
-	return c
+	if c.allowCredentials && c.allowsAnyOrigin() {
+		return nil, ErrCredentialsWithAnyOrigin
+	}
+
+	return c, nil
 }
 
+// Wrap returns a handler that adds the CORS headers to the response before
+// calling h. The headers are only added when the Origin of the request
+// matches the configured origins, in which case only that origin is echoed
+// back. Preflight requests are answered directly and never reach h.
 func (c *Cors) Wrap(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if len(c.allowedOrigins) > 0 {
-			w.Header().Add("Access-Control-Allow-Origin", strings.Join(c.allowedOrigins, ", "))
+		if isPreflight(r) {
+			c.handlePreflight(w, r)
+			return
 		}
-		if len(c.allowedMethods) > 0 {
-			w.Header().Add("Access-Control-Allow-Methods", strings.Join(c.allowedMethods, ", "))
+
+		var origin string
+		if r != nil {
+			origin = r.Header.Get("Origin")
 		}
-		if len(c.allowedHeaders) > 0 {
-			w.Header().Add("Access-Control-Allow-Headers", strings.Join(c.allowedHeaders, ", "))
+
+		if !c.allowsAnyOrigin() {
+			w.Header().Add("Vary", "Origin")
 		}
 
-		if r != nil && r.Method == http.MethodOptions {
-			if c.maxAge > 0 {
-				w.Header().Add("Access-Control-Max-Age", fmt.Sprint(int(c.maxAge.Seconds())))
+		if origin != "" {
+			allowed, err := c.resolveOrigin(r, origin)
+			if err != nil {
+				c.errorHandler(w, r, err)
+				return
+			}
+			if allowed {
+				c.addAllowHeaders(w, origin)
+				if len(c.exposedHeaders) > 0 {
+					w.Header().Add("Access-Control-Expose-Headers", strings.Join(c.exposedHeaders, ", "))
+				}
+			} else {
+				c.reject(OriginNotAllowed, r)
 			}
-			w.WriteHeader(http.StatusNoContent)
-			return
 		}
 
 		h.ServeHTTP(w, r)
 	})
 }
 
-// WithOrigins returns a ConfigFunc that configures the Cors to output a
-// header that signals that only requests from the given hosts are accepted.
+// handlePreflight validates the requested origin, method and headers of a
+// preflight request against the configuration and responds with either the
+// CORS headers or the reject status.
+func (c *Cors) handlePreflight(w http.ResponseWriter, r *http.Request) {
+	if !c.allowsAnyOrigin() {
+		w.Header().Add("Vary", "Origin")
+	}
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	origin := r.Header.Get("Origin")
+	method := r.Header.Get("Access-Control-Request-Method")
+	headers := requestedHeaders(r)
+
+	if !validToken(method) {
+		c.rejectPreflight(w, r, MalformedPreflight)
+		return
+	}
+	for _, header := range headers {
+		if !validToken(header) {
+			c.rejectPreflight(w, r, MalformedPreflight)
+			return
+		}
+	}
+
+	allowed, err := c.resolveOrigin(r, origin)
+	if err != nil {
+		c.errorHandler(w, r, err)
+		return
+	}
+	if !allowed {
+		c.rejectPreflight(w, r, OriginNotAllowed)
+		return
+	}
+
+	if !c.methodAllowed(method) {
+		c.rejectPreflight(w, r, MethodNotAllowed)
+		return
+	}
+
+	for _, header := range headers {
+		if !c.headerAllowed(header) {
+			c.rejectPreflight(w, r, HeaderNotAllowed)
+			return
+		}
+	}
+
+	c.addAllowHeaders(w, origin)
+	if c.allowPrivateNetwork && r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+		w.Header().Add("Access-Control-Allow-Private-Network", "true")
+	}
+	if c.maxAge > 0 {
+		w.Header().Add("Access-Control-Max-Age", fmt.Sprint(int(c.maxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Cors) rejectPreflight(w http.ResponseWriter, r *http.Request, reason Reason) {
+	c.reject(reason, r)
+	w.WriteHeader(c.rejectStatus)
+}
+
+func (c *Cors) addAllowHeaders(w http.ResponseWriter, origin string) {
+	if c.allowsAnyOrigin() {
+		w.Header().Add("Access-Control-Allow-Origin", wildcardOrigin)
+	} else {
+		w.Header().Add("Access-Control-Allow-Origin", origin)
+	}
+	if c.allowCredentials {
+		w.Header().Add("Access-Control-Allow-Credentials", "true")
+	}
+	if len(c.allowedMethods) > 0 {
+		w.Header().Add("Access-Control-Allow-Methods", strings.Join(c.allowedMethods, ", "))
+	}
+	if len(c.allowedHeaders) > 0 {
+		w.Header().Add("Access-Control-Allow-Headers", strings.Join(c.allowedHeaders, ", "))
+	}
+}
+
+// WithOrigins returns a ConfigFunc that configures the Cors to only accept
+// requests from the given origins. An origin can be given exactly (like
+// "https://example.com"), with a wildcard subdomain (like
+// "https://*.example.com") or as "*" to accept any origin.
 func WithOrigins(origins ...string) ConfigFunc {
 	return func(c *Cors) {
 		c.allowedOrigins = origins
 	}
 }
 
+// WithOriginPatterns returns a ConfigFunc that configures the Cors to also
+// accept requests from origins matching one of the given regular
+// expressions. A pattern must match the whole origin, as if anchored with
+// "^" and "$", so "example\\.com" does not accept
+// "https://example.com.evil.net".
+func WithOriginPatterns(patterns ...*regexp.Regexp) ConfigFunc {
+	return func(c *Cors) {
+		c.originPatterns = make([]*regexp.Regexp, len(patterns))
+		for i, p := range patterns {
+			c.originPatterns[i] = regexp.MustCompile("^(?:" + p.String() + ")$")
+		}
+	}
+}
+
 // WithMethods returns a ConfigFunc that configures the Cors to output
 // a header that signals that only requests with one of the given methods
 // are accepted.
@@ -90,3 +246,41 @@ func WithHeaders(headers ...string) ConfigFunc {
 		c.allowedHeaders = headers
 	}
 }
+
+// WithRejectStatus returns a ConfigFunc that configures the status code
+// used to answer preflight requests that are not allowed. The default is
+// http.StatusForbidden.
+func WithRejectStatus(code int) ConfigFunc {
+	return func(c *Cors) {
+		c.rejectStatus = code
+	}
+}
+
+// WithExposedHeaders returns a ConfigFunc that configures the Cors to output
+// a header that signals which response headers the browser may expose to
+// the calling script.
+func WithExposedHeaders(headers ...string) ConfigFunc {
+	return func(c *Cors) {
+		c.exposedHeaders = headers
+	}
+}
+
+// WithCredentials returns a ConfigFunc that configures the Cors to output
+// a header that signals that requests with credentials (cookies,
+// authorization headers or client certificates) are accepted. It can not be
+// combined with the "*" origin.
+func WithCredentials() ConfigFunc {
+	return func(c *Cors) {
+		c.allowCredentials = true
+	}
+}
+
+// WithPrivateNetwork returns a ConfigFunc that configures the Cors to answer
+// preflight requests asking for Private Network Access with a header that
+// signals that requests from public sites to this (private) network are
+// accepted.
+func WithPrivateNetwork() ConfigFunc {
+	return func(c *Cors) {
+		c.allowPrivateNetwork = true
+	}
+}
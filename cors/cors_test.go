@@ -4,13 +4,14 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"testing"
 	"time"
 )
 
 func TestCreation(t *testing.T) {
 	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
-	wrapped := New().Wrap(emptyHandler)
+	wrapped := newCors(t).Wrap(emptyHandler)
 	recorder := httptest.NewRecorder()
 	wrapped.ServeHTTP(recorder, nil)
 	validateHeaders("", "", "", "", recorder, t)
@@ -18,67 +19,333 @@ func TestCreation(t *testing.T) {
 
 func TestOrigin(t *testing.T) {
 	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
-	wrapped := New(WithOrigins("Foo")).Wrap(emptyHandler)
+	wrapped := newCors(t, WithOrigins("Foo")).Wrap(emptyHandler)
 	recorder := httptest.NewRecorder()
-	wrapped.ServeHTTP(recorder, nil)
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "Foo", t))
 	validateHeaders("Foo", "", "", "", recorder, t)
+
+	if recorder.Header().Get("Vary") != "Origin" {
+		t.Fatal("unexpected header for \"Vary\":", recorder.Header().Get("Vary"))
+	}
 }
 
 func TestOrigins(t *testing.T) {
 	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
-	wrapped := New(WithOrigins("Foo", "Bar")).Wrap(emptyHandler)
+	wrapped := newCors(t, WithOrigins("Foo", "Bar")).Wrap(emptyHandler)
+
 	recorder := httptest.NewRecorder()
-	wrapped.ServeHTTP(recorder, nil)
-	validateHeaders("Foo, Bar", "", "", "", recorder, t)
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "Foo", t))
+	validateHeaders("Foo", "", "", "", recorder, t)
+
+	recorder = httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "Bar", t))
+	validateHeaders("Bar", "", "", "", recorder, t)
+}
+
+func TestOriginNotAllowed(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	wrapped := newCors(t, WithOrigins("https://example.com"), WithMethods(http.MethodPut)).Wrap(emptyHandler)
+	recorder := httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "https://evil.com", t))
+	validateHeaders("", "", "", "", recorder, t)
+
+	if recorder.Header().Get("Vary") != "Origin" {
+		t.Fatal("unexpected header for \"Vary\":", recorder.Header().Get("Vary"))
+	}
+}
+
+func TestAnyOrigin(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	wrapped := newCors(t, WithOrigins("*")).Wrap(emptyHandler)
+	recorder := httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "https://example.com", t))
+	validateHeaders("*", "", "", "", recorder, t)
+
+	if recorder.Header().Get("Vary") != "" {
+		t.Fatal("unexpected header for \"Vary\":", recorder.Header().Get("Vary"))
+	}
+}
+
+func TestWildcardOrigin(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	wrapped := newCors(t, WithOrigins("https://*.example.com", "*.example.org")).Wrap(emptyHandler)
+
+	tests := map[string]string{
+		"https://app.example.com":     "https://app.example.com",
+		"https://a.b.example.com":     "https://a.b.example.com",
+		"http://app.example.com":      "",
+		"https://example.com":         "",
+		"https://app.example.com.org": "",
+		"https://evilexample.com":     "",
+		"http://app.example.org":      "http://app.example.org",
+	}
+
+	for origin, expected := range tests {
+		recorder := httptest.NewRecorder()
+		wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, origin, t))
+		validateHeaders(expected, "", "", "", recorder, t)
+	}
+}
+
+func TestOriginPattern(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	pattern := regexp.MustCompile(`^https://pr-\d+\.preview\.example\.com$`)
+	wrapped := newCors(t, WithOriginPatterns(pattern)).Wrap(emptyHandler)
+
+	recorder := httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "https://pr-42.preview.example.com", t))
+	validateHeaders("https://pr-42.preview.example.com", "", "", "", recorder, t)
+
+	recorder = httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "https://pr-x.preview.example.com", t))
+	validateHeaders("", "", "", "", recorder, t)
+}
+
+func TestUnanchoredOriginPattern(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	pattern := regexp.MustCompile(`https://(www\.)?example\.com`)
+	wrapped := newCors(t, WithOriginPatterns(pattern)).Wrap(emptyHandler)
+
+	for _, origin := range []string{"https://example.com", "https://www.example.com"} {
+		recorder := httptest.NewRecorder()
+		wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, origin, t))
+		validateHeaders(origin, "", "", "", recorder, t)
+	}
+
+	for _, origin := range []string{"https://example.com.evil.net", "https://evil.net/https://example.com", "xhttps://example.com"} {
+		recorder := httptest.NewRecorder()
+		wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, origin, t))
+		validateHeaders("", "", "", "", recorder, t)
+	}
 }
 
 func TestMethod(t *testing.T) {
 	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
-	corsMw := New(WithMethods(http.MethodPut))
+	corsMw := newCors(t, WithOrigins("Foo"), WithMethods(http.MethodPut))
 	wrapped := corsMw.Wrap(emptyHandler)
 	recorder := httptest.NewRecorder()
-	wrapped.ServeHTTP(recorder, nil)
-	validateHeaders("", http.MethodPut, "", "", recorder, t)
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "Foo", t))
+	validateHeaders("Foo", http.MethodPut, "", "", recorder, t)
 }
 
 func TestMethods(t *testing.T) {
 	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
-	corsMw := New(WithMethods(http.MethodDelete, http.MethodPost))
+	corsMw := newCors(t, WithOrigins("Foo"), WithMethods(http.MethodDelete, http.MethodPost))
 	wrapped := corsMw.Wrap(emptyHandler)
 	recorder := httptest.NewRecorder()
-	wrapped.ServeHTTP(recorder, nil)
-	validateHeaders("", fmt.Sprintf("%s, %s", http.MethodDelete, http.MethodPost), "", "", recorder, t)
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "Foo", t))
+	validateHeaders("Foo", fmt.Sprintf("%s, %s", http.MethodDelete, http.MethodPost), "", "", recorder, t)
 }
 
 func TestHeader(t *testing.T) {
 	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
-	corsMw := New(WithHeaders("X-Foo"))
+	corsMw := newCors(t, WithOrigins("Foo"), WithHeaders("X-Foo"))
 	wrapped := corsMw.Wrap(emptyHandler)
 	recorder := httptest.NewRecorder()
-	wrapped.ServeHTTP(recorder, nil)
-	validateHeaders("", "", "X-Foo", "", recorder, t)
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "Foo", t))
+	validateHeaders("Foo", "", "X-Foo", "", recorder, t)
 }
 
 func TestHeaders(t *testing.T) {
 	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
-	corsMw := New(WithHeaders("X-Foo", "X-Bar"))
+	corsMw := newCors(t, WithOrigins("Foo"), WithHeaders("X-Foo", "X-Bar"))
 	wrapped := corsMw.Wrap(emptyHandler)
 	recorder := httptest.NewRecorder()
-	wrapped.ServeHTTP(recorder, nil)
-	validateHeaders("", "", "X-Foo, X-Bar", "", recorder, t)
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "Foo", t))
+	validateHeaders("Foo", "", "X-Foo, X-Bar", "", recorder, t)
 }
 
 func TestAge(t *testing.T) {
 	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
-	corsMw := New(WithMaxAge(time.Hour))
+	corsMw := newCors(t, WithOrigins("Foo"), WithMaxAge(time.Hour))
 	wrapped := corsMw.Wrap(emptyHandler)
 	recorder := httptest.NewRecorder()
-	req, err := http.NewRequest(http.MethodOptions, "", nil)
+	wrapped.ServeHTTP(recorder, newPreflight("Foo", http.MethodGet, "", t))
+	validateHeaders("Foo", "", "", fmt.Sprint(time.Hour.Seconds()), recorder, t)
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatal("unexpected status code:", recorder.Code)
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	corsMw := newCors(t,
+		WithOrigins("Foo"),
+		WithMethods(http.MethodPut, http.MethodDelete),
+		WithHeaders("X-Foo", "Content-Type"),
+	)
+	wrapped := corsMw.Wrap(handler)
+
+	recorder := httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newPreflight("Foo", http.MethodPut, "x-foo, content-type", t))
+	validateHeaders("Foo", "PUT, DELETE", "X-Foo, Content-Type", "", recorder, t)
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatal("unexpected status code:", recorder.Code)
+	}
+
+	if called {
+		t.Fatal("preflight request reached the handler")
+	}
+}
+
+func TestPreflightRejected(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("rejected preflight request reached the handler")
+	})
+	corsMw := newCors(t,
+		WithOrigins("Foo"),
+		WithMethods(http.MethodPut),
+		WithHeaders("X-Foo"),
+		WithRejectStatus(http.StatusBadRequest),
+	)
+	wrapped := corsMw.Wrap(handler)
+
+	tests := []struct {
+		origin, method, headers string
+	}{
+		{"Bar", http.MethodPut, ""},
+		{"Foo", http.MethodDelete, ""},
+		{"Foo", http.MethodPut, "X-Foo, X-Bar"},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		wrapped.ServeHTTP(recorder, newPreflight(test.origin, test.method, test.headers, t))
+		validateHeaders("", "", "", "", recorder, t)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatal("unexpected status code:", recorder.Code)
+		}
+	}
+}
+
+func TestPlainOptionsPassesThrough(t *testing.T) {
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	wrapped := newCors(t, WithOrigins("Foo"), WithMaxAge(time.Hour)).Wrap(handler)
+
+	recorder := httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodOptions, "Foo", t))
+	validateHeaders("Foo", "", "", "", recorder, t)
+
+	if !called {
+		t.Fatal("plain OPTIONS request did not reach the handler")
+	}
+}
+
+func newCors(t *testing.T, configs ...ConfigFunc) *Cors {
+	t.Helper()
+
+	c, err := NewChecked(configs...)
 	if err != nil {
 		t.Fatal(err)
 	}
+	return c
+}
+
+func newRequest(method, origin string, t *testing.T) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(method, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", origin)
+	return req
+}
+
+func TestCredentials(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	wrapped := newCors(t, WithOrigins("Foo"), WithCredentials()).Wrap(emptyHandler)
+
+	recorder := httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "Foo", t))
+	validateHeaders("Foo", "", "", "", recorder, t)
+
+	if recorder.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatal("unexpected header for \"Access-Control-Allow-Credentials\":", recorder.Header().Get("Access-Control-Allow-Credentials"))
+	}
+
+	recorder = httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "Bar", t))
+
+	if recorder.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatal("unexpected header for \"Access-Control-Allow-Credentials\":", recorder.Header().Get("Access-Control-Allow-Credentials"))
+	}
+}
+
+func TestCredentialsWithAnyOrigin(t *testing.T) {
+	_, err := NewChecked(WithOrigins("Foo", "*"), WithCredentials())
+	if err != ErrCredentialsWithAnyOrigin {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if r := recover(); r != ErrCredentialsWithAnyOrigin {
+			t.Fatal("unexpected panic:", r)
+		}
+	}()
+	New(WithOrigins("Foo", "*"), WithCredentials())
+}
+
+func TestNew(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	wrapped := New(WithOrigins("Foo")).Wrap(emptyHandler)
+
+	recorder := httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "Foo", t))
+	validateHeaders("Foo", "", "", "", recorder, t)
+}
+
+func TestExposedHeaders(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	wrapped := newCors(t, WithOrigins("Foo"), WithExposedHeaders("X-Foo", "X-Bar")).Wrap(emptyHandler)
+
+	recorder := httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "Foo", t))
+
+	if recorder.Header().Get("Access-Control-Expose-Headers") != "X-Foo, X-Bar" {
+		t.Fatal("unexpected header for \"Access-Control-Expose-Headers\":", recorder.Header().Get("Access-Control-Expose-Headers"))
+	}
+}
+
+func TestPrivateNetwork(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	wrapped := newCors(t, WithOrigins("Foo"), WithPrivateNetwork()).Wrap(emptyHandler)
+
+	recorder := httptest.NewRecorder()
+	req := newPreflight("Foo", http.MethodGet, "", t)
+	req.Header.Set("Access-Control-Request-Private-Network", "true")
 	wrapped.ServeHTTP(recorder, req)
-	validateHeaders("", "", "", fmt.Sprint(time.Hour.Seconds()), recorder, t)
+
+	if recorder.Header().Get("Access-Control-Allow-Private-Network") != "true" {
+		t.Fatal("unexpected header for \"Access-Control-Allow-Private-Network\":", recorder.Header().Get("Access-Control-Allow-Private-Network"))
+	}
+
+	recorder = httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newPreflight("Foo", http.MethodGet, "", t))
+
+	if recorder.Header().Get("Access-Control-Allow-Private-Network") != "" {
+		t.Fatal("unexpected header for \"Access-Control-Allow-Private-Network\":", recorder.Header().Get("Access-Control-Allow-Private-Network"))
+	}
+}
+
+func newPreflight(origin, method, headers string, t *testing.T) *http.Request {
+	t.Helper()
+
+	req := newRequest(http.MethodOptions, origin, t)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	return req
 }
 
 func validateHeaders(originVal, methodsVal, headersVal, ageVal string, recorder *httptest.ResponseRecorder, t *testing.T) {
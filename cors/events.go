@@ -0,0 +1,71 @@
+package cors
+
+import (
+	"net/http"
+)
+
+// Reason describes why a cross-origin request was refused.
+type Reason int
+
+const (
+	// OriginNotAllowed is used when the Origin of the request is not
+	// allowed.
+	OriginNotAllowed Reason = iota
+	// MethodNotAllowed is used when a preflight request asks for a method
+	// that is not allowed.
+	MethodNotAllowed
+	// HeaderNotAllowed is used when a preflight request asks for a header
+	// that is not allowed.
+	HeaderNotAllowed
+	// MalformedPreflight is used when a preflight request asks for an
+	// invalid method or header name.
+	MalformedPreflight
+)
+
+// String returns a human readable description of the reason.
+func (r Reason) String() string {
+	switch r {
+	case OriginNotAllowed:
+		return "origin not allowed"
+	case MethodNotAllowed:
+		return "method not allowed"
+	case HeaderNotAllowed:
+		return "header not allowed"
+	case MalformedPreflight:
+		return "malformed preflight"
+	}
+	return "unknown"
+}
+
+// EventFunc is the signature of the functions that can be given to be
+// notified when a cross-origin request is refused.
+type EventFunc func(reason Reason, r *http.Request)
+
+// WithEventFunc returns a ConfigFunc that configures the Cors to call the
+// given function every time a cross-origin request is refused. Note that
+// browsers also send the Origin header on some same-origin requests, so
+// these are reported as well if the origin is not allowed.
+func WithEventFunc(fn EventFunc) ConfigFunc {
+	return func(c *Cors) {
+		c.eventFunc = fn
+	}
+}
+
+// Rejected returns the number of requests that have been refused for the
+// given reason.
+func (c *Cors) Rejected(reason Reason) int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.rejected[reason]
+}
+
+// reject records the rejection and notifies the EventFunc (if any).
+func (c *Cors) reject(reason Reason, r *http.Request) {
+	c.mutex.Lock()
+	c.rejected[reason]++
+	c.mutex.Unlock()
+
+	if c.eventFunc != nil {
+		c.eventFunc(reason, r)
+	}
+}
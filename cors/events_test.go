@@ -0,0 +1,70 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRejectionEvents(t *testing.T) {
+	var reasons []Reason
+	eventFn := func(reason Reason, r *http.Request) {
+		reasons = append(reasons, reason)
+	}
+
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	corsMw := newCors(t,
+		WithOrigins("Foo"),
+		WithMethods(http.MethodPut),
+		WithHeaders("X-Foo"),
+		WithEventFunc(eventFn),
+	)
+	wrapped := corsMw.Wrap(emptyHandler)
+
+	requests := []*http.Request{
+		newRequest(http.MethodGet, "Foo", t),
+		newRequest(http.MethodGet, "Bar", t),
+		newPreflight("Bar", http.MethodPut, "", t),
+		newPreflight("Foo", http.MethodDelete, "", t),
+		newPreflight("Foo", http.MethodPut, "X-Bar", t),
+		newPreflight("Foo", "PU T", "", t),
+		newPreflight("Foo", http.MethodPut, "X-Foo, X:Bar", t),
+		newPreflight("Foo", http.MethodPut, "X-Foo", t),
+	}
+
+	for _, req := range requests {
+		wrapped.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	expected := []Reason{
+		OriginNotAllowed,
+		OriginNotAllowed,
+		MethodNotAllowed,
+		HeaderNotAllowed,
+		MalformedPreflight,
+		MalformedPreflight,
+	}
+
+	if len(reasons) != len(expected) {
+		t.Fatal("unexpected events:", reasons)
+	}
+
+	for i := range expected {
+		if reasons[i] != expected[i] {
+			t.Fatal("unexpected event:", reasons[i])
+		}
+	}
+
+	counts := map[Reason]int64{
+		OriginNotAllowed:   2,
+		MethodNotAllowed:   1,
+		HeaderNotAllowed:   1,
+		MalformedPreflight: 2,
+	}
+
+	for reason, count := range counts {
+		if corsMw.Rejected(reason) != count {
+			t.Fatal("unexpected count for", reason, corsMw.Rejected(reason))
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package cors
+
+import (
+	"strings"
+)
+
+const (
+	wildcardOrigin = "*"
+)
+
+// originAllowed reports whether the given origin matches one of the
+// configured origins or origin patterns.
+func (c *Cors) originAllowed(origin string) bool {
+	for _, allowed := range c.allowedOrigins {
+		if allowed == wildcardOrigin || strings.EqualFold(allowed, origin) || matchWildcard(allowed, origin) {
+			return true
+		}
+	}
+
+	for _, p := range c.originPatterns {
+		if p.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowsAnyOrigin reports whether the wildcard origin has been configured.
+func (c *Cors) allowsAnyOrigin() bool {
+	for _, allowed := range c.allowedOrigins {
+		if allowed == wildcardOrigin {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcard matches origins against a pattern like "*.example.com" or
+// "https://*.example.com". The wildcard only matches subdomains, so the
+// pattern above does not match "https://example.com" itself. If the
+// pattern has no scheme any scheme is accepted.
+func matchWildcard(pattern, origin string) bool {
+	index := strings.Index(pattern, "*.")
+	if index == -1 {
+		return false
+	}
+
+	scheme, suffix := pattern[:index], strings.ToLower(pattern[index+1:])
+	origin = strings.ToLower(origin)
+
+	if scheme != "" {
+		if !strings.HasPrefix(origin, strings.ToLower(scheme)) {
+			return false
+		}
+		origin = origin[len(scheme):]
+	} else if i := strings.Index(origin, "://"); i != -1 {
+		origin = origin[i+3:]
+	}
+
+	return len(origin) > len(suffix) && strings.HasSuffix(origin, suffix)
+}
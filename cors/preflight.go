@@ -0,0 +1,76 @@
+package cors
+
+import (
+	"net/http"
+	"strings"
+)
+
+var (
+	// safelistedMethods are always allowed as they never require a
+	// preflight on their own.
+	safelistedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+)
+
+// isPreflight reports whether r is a CORS preflight request. Plain OPTIONS
+// requests without the Origin and Access-Control-Request-Method headers are
+// not preflights.
+func isPreflight(r *http.Request) bool {
+	return r != nil &&
+		r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// requestedHeaders returns the headers listed in the
+// Access-Control-Request-Headers header(s) of the request.
+func requestedHeaders(r *http.Request) []string {
+	var headers []string
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, header := range strings.Split(value, ",") {
+			header = strings.TrimSpace(header)
+			if header != "" {
+				headers = append(headers, header)
+			}
+		}
+	}
+	return headers
+}
+
+// validToken reports whether s is a valid HTTP token (RFC 7230), which both
+// method and header names must be.
+func validToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !isTokenChar(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isTokenChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
+
+func (c *Cors) methodAllowed(method string) bool {
+	return containsFold(safelistedMethods, method) || containsFold(c.allowedMethods, method)
+}
+
+func (c *Cors) headerAllowed(header string) bool {
+	return containsFold(c.allowedHeaders, "*") || containsFold(c.allowedHeaders, header)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
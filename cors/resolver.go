@@ -0,0 +1,101 @@
+package cors
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultOriginCacheSize is the number of origins cached by
+// WithOriginCache when no size is given.
+const DefaultOriginCacheSize = 1024
+
+// OriginResolverFunc is the signature of the functions that can be given
+// to decide dynamically if an origin is allowed. It is only called for
+// origins that are not allowed by the static configuration.
+type OriginResolverFunc func(r *http.Request, origin string) (bool, error)
+
+// ErrorHandlerFunc is the signature of the functions that can be given to
+// respond to requests when the OriginResolverFunc fails.
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+// WithOriginResolver returns a ConfigFunc that configures the Cors to ask
+// the given function whether origins not allowed by WithOrigins or
+// WithOriginPatterns are allowed.
+func WithOriginResolver(fn OriginResolverFunc) ConfigFunc {
+	return func(c *Cors) {
+		c.originResolver = fn
+	}
+}
+
+// WithOriginCache returns a ConfigFunc that configures the Cors to cache
+// the decisions of the OriginResolverFunc for the given amount of time.
+// Decisions are cached per origin, so the resolver must not base its
+// decision on other parts of the request. The cache holds at most size
+// origins. Origins are chosen by the client, so the cache is always
+// bounded: a size of zero or less means DefaultOriginCacheSize. Errors are
+// never cached.
+func WithOriginCache(ttl time.Duration, size int) ConfigFunc {
+	return func(c *Cors) {
+		if size <= 0 {
+			size = DefaultOriginCacheSize
+		}
+		c.cache = newOriginCache(ttl, size)
+	}
+}
+
+// WithErrorHandler returns a ConfigFunc that configures the function used
+// to respond when the OriginResolverFunc returns an error. The default
+// responds with http.StatusInternalServerError.
+func WithErrorHandler(fn ErrorHandlerFunc) ConfigFunc {
+	return func(c *Cors) {
+		c.errorHandler = fn
+	}
+}
+
+// InvalidateOrigin removes the cached decision for the given origin so the
+// OriginResolverFunc is asked again on the next request.
+func (c *Cors) InvalidateOrigin(origin string) {
+	if c.cache != nil {
+		c.cache.remove(origin)
+	}
+}
+
+// InvalidateOrigins removes all cached decisions.
+func (c *Cors) InvalidateOrigins() {
+	if c.cache != nil {
+		c.cache.clear()
+	}
+}
+
+// resolveOrigin reports whether the origin is allowed by either the static
+// configuration or the OriginResolverFunc.
+func (c *Cors) resolveOrigin(r *http.Request, origin string) (bool, error) {
+	if c.originAllowed(origin) {
+		return true, nil
+	}
+
+	if c.originResolver == nil {
+		return false, nil
+	}
+
+	if c.cache != nil {
+		if allowed, ok := c.cache.get(origin); ok {
+			return allowed, nil
+		}
+	}
+
+	allowed, err := c.originResolver(r, origin)
+	if err != nil {
+		return false, err
+	}
+
+	if c.cache != nil {
+		c.cache.set(origin, allowed)
+	}
+
+	return allowed, nil
+}
+
+func defaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
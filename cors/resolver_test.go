@@ -0,0 +1,141 @@
+package cors
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestOriginResolver(t *testing.T) {
+	calls := 0
+	resolver := func(r *http.Request, origin string) (bool, error) {
+		calls++
+		return origin == "https://tenant.example.com", nil
+	}
+
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	wrapped := newCors(t, WithOrigins("Foo"), WithOriginResolver(resolver)).Wrap(emptyHandler)
+
+	recorder := httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "Foo", t))
+	validateHeaders("Foo", "", "", "", recorder, t)
+
+	if calls != 0 {
+		t.Fatal("resolver called for statically allowed origin")
+	}
+
+	recorder = httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "https://tenant.example.com", t))
+	validateHeaders("https://tenant.example.com", "", "", "", recorder, t)
+
+	recorder = httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "https://other.example.com", t))
+	validateHeaders("", "", "", "", recorder, t)
+
+	if calls != 2 {
+		t.Fatal("unexpected number of resolver calls:", calls)
+	}
+}
+
+func TestOriginResolverCache(t *testing.T) {
+	calls := 0
+	resolver := func(r *http.Request, origin string) (bool, error) {
+		calls++
+		return true, nil
+	}
+
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	corsMw := newCors(t, WithOriginResolver(resolver), WithOriginCache(time.Minute, 2))
+	wrapped := corsMw.Wrap(emptyHandler)
+
+	now := time.Now()
+	corsMw.cache.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		wrapped.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodGet, "A", t))
+	}
+	if calls != 1 {
+		t.Fatal("unexpected number of resolver calls:", calls)
+	}
+
+	corsMw.InvalidateOrigin("A")
+	wrapped.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodGet, "A", t))
+	if calls != 2 {
+		t.Fatal("unexpected number of resolver calls after invalidation:", calls)
+	}
+
+	now = now.Add(time.Minute)
+	wrapped.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodGet, "A", t))
+	if calls != 3 {
+		t.Fatal("unexpected number of resolver calls after expiry:", calls)
+	}
+
+	wrapped.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodGet, "B", t))
+	wrapped.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodGet, "C", t))
+	if len(corsMw.cache.entries) != 2 {
+		t.Fatal("unexpected cache size:", len(corsMw.cache.entries))
+	}
+
+	wrapped.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodGet, "A", t))
+	if calls != 6 {
+		t.Fatal("unexpected number of resolver calls after eviction:", calls)
+	}
+
+	corsMw.InvalidateOrigins()
+	if len(corsMw.cache.entries) != 0 {
+		t.Fatal("unexpected cache size after invalidation:", len(corsMw.cache.entries))
+	}
+}
+
+func TestOriginResolverCacheBounded(t *testing.T) {
+	resolver := func(r *http.Request, origin string) (bool, error) {
+		return true, nil
+	}
+
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	corsMw := newCors(t, WithOriginResolver(resolver), WithOriginCache(time.Minute, 0))
+	wrapped := corsMw.Wrap(emptyHandler)
+
+	for i := 0; i < DefaultOriginCacheSize+10; i++ {
+		wrapped.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodGet, strconv.Itoa(i), t))
+	}
+	if len(corsMw.cache.entries) != DefaultOriginCacheSize {
+		t.Fatal("unexpected cache size:", len(corsMw.cache.entries))
+	}
+}
+
+func TestOriginResolverError(t *testing.T) {
+	resolver := func(r *http.Request, origin string) (bool, error) {
+		return false, errors.New("tenant database unavailable")
+	}
+
+	var handledErr error
+	errorHandler := func(w http.ResponseWriter, r *http.Request, err error) {
+		handledErr = err
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request reached the handler despite resolver error")
+	})
+	corsMw := newCors(t, WithOriginResolver(resolver), WithOriginCache(time.Minute, 0), WithErrorHandler(errorHandler))
+	wrapped := corsMw.Wrap(handler)
+
+	recorder := httptest.NewRecorder()
+	wrapped.ServeHTTP(recorder, newRequest(http.MethodGet, "A", t))
+
+	if handledErr == nil {
+		t.Fatal("error handler not called")
+	}
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatal("unexpected status code:", recorder.Code)
+	}
+
+	if len(corsMw.cache.entries) != 0 {
+		t.Fatal("error was cached")
+	}
+}
@@ -0,0 +1,122 @@
+package header
+
+import (
+	"strings"
+)
+
+// Sources commonly used in Content-Security-Policy directives.
+const (
+	Self          = "'self'"
+	None          = "'none'"
+	UnsafeInline  = "'unsafe-inline'"
+	UnsafeEval    = "'unsafe-eval'"
+	StrictDynamic = "'strict-dynamic'"
+
+	// NonceSource is replaced by the nonce of the request, like
+	// "'nonce-cmFuZG9t'", when the policy is written. It is left out if the
+	// request has no nonce.
+	NonceSource = "'nonce'"
+)
+
+// CSP builds a Content-Security-Policy. The directives are written in the
+// order they are first given:
+//
+//	csp := NewCSP().
+//		DefaultSrc(Self).
+//		ScriptSrc(Self, NonceSource).
+//		ObjectSrc(None)
+type CSP struct {
+	directives []directive
+}
+
+type directive struct {
+	name    string
+	sources []string
+}
+
+// NewCSP creates an empty CSP.
+func NewCSP() *CSP {
+	return &CSP{}
+}
+
+// Directive adds the sources to the named directive. A directive can be
+// given without sources, like "upgrade-insecure-requests".
+func (c *CSP) Directive(name string, sources ...string) *CSP {
+	name = strings.ToLower(name)
+	for i := range c.directives {
+		if c.directives[i].name == name {
+			c.directives[i].sources = append(c.directives[i].sources, sources...)
+			return c
+		}
+	}
+	c.directives = append(c.directives, directive{name: name, sources: sources})
+	return c
+}
+
+func (c *CSP) DefaultSrc(sources ...string) *CSP { return c.Directive("default-src", sources...) }
+
+func (c *CSP) ScriptSrc(sources ...string) *CSP { return c.Directive("script-src", sources...) }
+
+func (c *CSP) StyleSrc(sources ...string) *CSP { return c.Directive("style-src", sources...) }
+
+func (c *CSP) ImgSrc(sources ...string) *CSP { return c.Directive("img-src", sources...) }
+
+func (c *CSP) FontSrc(sources ...string) *CSP { return c.Directive("font-src", sources...) }
+
+func (c *CSP) ConnectSrc(sources ...string) *CSP { return c.Directive("connect-src", sources...) }
+
+func (c *CSP) MediaSrc(sources ...string) *CSP { return c.Directive("media-src", sources...) }
+
+func (c *CSP) ObjectSrc(sources ...string) *CSP { return c.Directive("object-src", sources...) }
+
+func (c *CSP) FrameSrc(sources ...string) *CSP { return c.Directive("frame-src", sources...) }
+
+func (c *CSP) FrameAncestors(sources ...string) *CSP {
+	return c.Directive("frame-ancestors", sources...)
+}
+
+func (c *CSP) BaseURI(sources ...string) *CSP { return c.Directive("base-uri", sources...) }
+
+func (c *CSP) FormAction(sources ...string) *CSP { return c.Directive("form-action", sources...) }
+
+// ReportURI sets the URI violations of the policy are reported to.
+func (c *CSP) ReportURI(uri string) *CSP { return c.Directive("report-uri", uri) }
+
+// UpgradeInsecureRequests makes browsers fetch http resources with https.
+func (c *CSP) UpgradeInsecureRequests() *CSP { return c.Directive("upgrade-insecure-requests") }
+
+// String returns the policy without nonces.
+func (c *CSP) String() string {
+	return c.render("")
+}
+
+// usesNonce reports whether a directive of the policy has NonceSource.
+func (c *CSP) usesNonce() bool {
+	for _, d := range c.directives {
+		for _, source := range d.sources {
+			if source == NonceSource {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// render returns the policy with NonceSource replaced by the nonce.
+func (c *CSP) render(nonce string) string {
+	parts := make([]string, 0, len(c.directives))
+	for _, d := range c.directives {
+		part := []string{d.name}
+		for _, source := range d.sources {
+			if source == NonceSource {
+				if nonce == "" {
+					continue
+				}
+				source = "'nonce-" + nonce + "'"
+			}
+			part = append(part, source)
+		}
+		parts = append(parts, strings.Join(part, " "))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -12,6 +12,14 @@ type Header struct {
 	staticHeaders           map[string]string
 	dynamicHeaderFuncs      []DynamicHeaderFunction
 	dynamicMultiHeaderFuncs []DynamicMultiHeaderFunction
+	rules                   []rule
+	responseRules           []rule
+	requestRules            []rule
+	precedence              Precedence
+
+	// prepareFuncs return the request passed on to the dynamic headers and
+	// the handler.
+	prepareFuncs []func(r *http.Request) *http.Request
 }
 
 type ConfigFunc func(*Header)
@@ -22,6 +30,12 @@ type DynamicHeaderFunction func(r *http.Request) (header, value string)
 
 type DynamicMultiHeaderFunction func(r *http.Request) (headers map[string]string)
 
+// ResponseHeaderFunction is the signature of the functions that can be
+// given to resolve header values from the response. They are called just
+// before the response is written with the status code and the headers set
+// by the handler. No header is added if an empty header name is returned.
+type ResponseHeaderFunction func(r *http.Request, status int, headers http.Header) (header, value string)
+
 // New creates a new Header with the given static headers and the given
 // dynamic header functions.
 func New(config ...ConfigFunc) *Header {
@@ -39,20 +53,68 @@ func New(config ...ConfigFunc) *Header {
 // used to wrap handlers.
 func (hm *Header) Wrap(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		for header, value := range hm.staticHeaders {
-			w.Header().Add(header, value)
-		}
-		for _, hFn := range hm.dynamicHeaderFuncs {
-			header, value := hFn(r)
-			w.Header().Add(header, value)
+		if r != nil {
+			if len(hm.requestRules) > 0 {
+				r = hm.applyRequest(r)
+			}
+			for _, pFn := range hm.prepareFuncs {
+				r = pFn(r)
+			}
 		}
-		for _, hMFn := range hm.dynamicMultiHeaderFuncs {
-			headers := hMFn(r)
-			for h, v := range headers {
-				w.Header().Add(h, v)
+
+		all := hm.precedence == MiddlewarePrecedence
+		if !all {
+			hm.apply(w.Header(), r)
+			if len(hm.responseRules) == 0 {
+				h.ServeHTTP(w, r)
+				return
 			}
 		}
-		h.ServeHTTP(w, r)
+
+		ww, rw := hm.wrapWriter(w, r, all)
+		h.ServeHTTP(ww, r)
+		rw.finish()
+	})
+}
+
+// apply applies the static and dynamic headers to the response headers.
+// With MiddlewarePrecedence they replace the headers set by the handler,
+// otherwise they are added.
+func (hm *Header) apply(headers http.Header, r *http.Request) {
+	add := headers.Add
+	if hm.precedence == MiddlewarePrecedence {
+		add = headers.Set
+	}
+
+	for header, value := range hm.staticHeaders {
+		add(header, value)
+	}
+	for _, hFn := range hm.dynamicHeaderFuncs {
+		header, value := hFn(r)
+		add(header, value)
+	}
+	for _, hMFn := range hm.dynamicMultiHeaderFuncs {
+		for h, v := range hMFn(r) {
+			add(h, v)
+		}
+	}
+	for _, rl := range hm.rules {
+		header, value := rl.resolve(r)
+		rl.op.apply(headers, header, value)
+	}
+}
+
+// wrapWriter returns a writer adding the response headers, and the static
+// and dynamic headers if all is true, just before the response is written.
+func (hm *Header) wrapWriter(w http.ResponseWriter, r *http.Request, all bool) (http.ResponseWriter, *responseWriter) {
+	return wrapWriter(w, func(status int) {
+		if all {
+			hm.apply(w.Header(), r)
+		}
+		for _, rl := range hm.responseRules {
+			header, value := rl.response(r, status, w.Header())
+			rl.op.apply(w.Header(), header, value)
+		}
 	})
 }
 
@@ -68,6 +130,13 @@ func WithDynamicMultiHeaderFunc(dMFn DynamicMultiHeaderFunction) ConfigFunc {
 	}
 }
 
+// WithResponseHeaderFunc returns a ConfigFunc that adds the header returned
+// by rFn when the response is written, so it can depend on the status code
+// and the headers of the response.
+func WithResponseHeaderFunc(rFn ResponseHeaderFunction) ConfigFunc {
+	return WithResponseHeaderRule(Add, rFn)
+}
+
 func WithStaticHeader(header, value string) ConfigFunc {
 	return func(h *Header) {
 		h.staticHeaders[header] = value
@@ -0,0 +1,40 @@
+package header
+
+import (
+	"net/http"
+)
+
+// WithRequestHeaderRule returns a ConfigFunc that changes the header of the
+// request with the given operation before the handler is called, like
+// WithRequestHeaderRule(Delete, "X-User-Id", "") to drop a header clients
+// must not send. The handler is given a copy of the request, the request
+// itself is never modified.
+func WithRequestHeaderRule(op Operation, header, value string) ConfigFunc {
+	return func(h *Header) {
+		h.requestRules = append(h.requestRules, rule{op: op, header: header, value: value})
+	}
+}
+
+// WithDynamicRequestHeaderRule returns a ConfigFunc that changes the
+// header of the request returned by dFn with the given operation before
+// the handler is called. dFn is given the request as changed by the rules
+// before it.
+func WithDynamicRequestHeaderRule(op Operation, dFn DynamicHeaderFunction) ConfigFunc {
+	return func(h *Header) {
+		h.requestRules = append(h.requestRules, rule{op: op, dynamic: dFn})
+	}
+}
+
+// applyRequest returns a copy of the request with the request rules
+// applied.
+func (hm *Header) applyRequest(r *http.Request) *http.Request {
+	r = r.Clone(r.Context())
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	for _, rl := range hm.requestRules {
+		header, value := rl.resolve(r)
+		rl.op.apply(r.Header, header, value)
+	}
+	return r
+}
@@ -0,0 +1,70 @@
+package header
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestHeaderRules(t *testing.T) {
+	var received http.Header
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	})
+
+	hMw := New(
+		WithRequestHeaderRule(Delete, "X-User-Id", ""),
+		WithRequestHeaderRule(Set, "X-Forwarded-Proto", "https"),
+		WithRequestHeaderRule(Add, "Via", "1.1 edge"),
+		WithRequestHeaderRule(SetIfAbsent, "X-Tenant", "default"),
+		WithDynamicRequestHeaderRule(Set, func(r *http.Request) (string, string) {
+			language := strings.ToLower(strings.TrimSpace(strings.Split(r.Header.Get("Accept-Language"), ",")[0]))
+			return "Accept-Language", language
+		}),
+	)
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-User-Id", "admin")
+	req.Header.Set("X-Forwarded-Proto", "http")
+	req.Header.Set("Via", "1.1 client")
+	req.Header.Set("Accept-Language", " DA-dk , en;q=0.8")
+
+	hMw.Wrap(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	if _, found := received["X-User-Id"]; found {
+		t.Fatal("Unexpected X-User-Id:", received.Get("X-User-Id"))
+	}
+
+	expected := map[string]string{
+		"X-Forwarded-Proto": "https",
+		"Via":               "1.1 client,1.1 edge",
+		"X-Tenant":          "default",
+		"Accept-Language":   "da-dk",
+	}
+	for header, value := range expected {
+		if got := strings.Join(received.Values(header), ","); got != value {
+			t.Fatal("Unexpected value for", header, got)
+		}
+	}
+
+	if req.Header.Get("X-User-Id") != "admin" || req.Header.Get("X-Forwarded-Proto") != "http" || len(req.Header.Values("Via")) != 1 {
+		t.Fatal("Unexpected change of the original request:", req.Header)
+	}
+	if _, found := req.Header["X-Tenant"]; found {
+		t.Fatal("Unexpected change of the original request:", req.Header)
+	}
+}
+
+func TestRequestHeaderRulesWithoutRequest(t *testing.T) {
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	New(WithRequestHeaderRule(Set, "X-Forwarded-Proto", "https")).Wrap(handler).ServeHTTP(httptest.NewRecorder(), nil)
+
+	if !called {
+		t.Fatal("Expected handler to be called")
+	}
+}
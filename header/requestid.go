@@ -0,0 +1,207 @@
+package header
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// RequestIDGenerator is the signature of the functions that make new
+// request IDs.
+type RequestIDGenerator func() string
+
+// RequestID holds the configuration of the request IDs handled by
+// WithRequestID and RequestIDTransport.
+type RequestID struct {
+	header    string
+	generator RequestIDGenerator
+	maxLength int
+	valid     func(id string) bool
+}
+
+// RequestIDConfigFunc is the type of function used to configure the
+// request IDs.
+type RequestIDConfigFunc func(*RequestID)
+
+func newRequestID(config []RequestIDConfigFunc) *RequestID {
+	rid := &RequestID{
+		header:    "X-Request-ID",
+		generator: UUIDv4,
+		maxLength: 64,
+	}
+	rid.valid = rid.validCharset
+
+	for _, confFn := range config {
+		confFn(rid)
+	}
+
+	return rid
+}
+
+// WithRequestID returns a ConfigFunc that gives every request an ID. The
+// ID given by the client in the X-Request-ID header is used if it is valid,
+// otherwise a new ID is made. The ID is set in the request header seen by
+// the handler, is available through RequestIDFromContext and is echoed in
+// the response header.
+func WithRequestID(config ...RequestIDConfigFunc) ConfigFunc {
+	rid := newRequestID(config)
+
+	return func(h *Header) {
+		h.prepareFuncs = append(h.prepareFuncs, rid.prepare)
+		WithDynamicHeaderRule(Set, func(r *http.Request) (string, string) {
+			if r == nil {
+				return "", ""
+			}
+			return rid.header, RequestIDFromContext(r.Context())
+		})(h)
+	}
+}
+
+// WithRequestIDHeader returns a RequestIDConfigFunc that configures the
+// name of the header holding the request ID.
+func WithRequestIDHeader(header string) RequestIDConfigFunc {
+	return func(rid *RequestID) {
+		rid.header = header
+	}
+}
+
+// WithRequestIDGenerator returns a RequestIDConfigFunc that configures the
+// function making new request IDs. The default is UUIDv4.
+func WithRequestIDGenerator(generator RequestIDGenerator) RequestIDConfigFunc {
+	return func(rid *RequestID) {
+		rid.generator = generator
+	}
+}
+
+// WithRequestIDMaxLength returns a RequestIDConfigFunc that configures the
+// maximum length of request IDs accepted from clients. The default is 64.
+func WithRequestIDMaxLength(length int) RequestIDConfigFunc {
+	return func(rid *RequestID) {
+		rid.maxLength = length
+	}
+}
+
+// WithRequestIDValidator returns a RequestIDConfigFunc that replaces the
+// check of the characters of request IDs accepted from clients. By default
+// only letters, digits and "-", "_", ".", and ":" are accepted so the IDs
+// can be logged safely. The maximum length is checked in either case.
+func WithRequestIDValidator(valid func(id string) bool) RequestIDConfigFunc {
+	return func(rid *RequestID) {
+		rid.valid = valid
+	}
+}
+
+// prepare returns the request with its ID in the context and in the
+// header.
+func (rid *RequestID) prepare(r *http.Request) *http.Request {
+	id := r.Header.Get(rid.header)
+	if id == "" || len(id) > rid.maxLength || !rid.valid(id) {
+		id = rid.generator()
+		r = r.Clone(r.Context())
+		if r.Header == nil {
+			r.Header = make(http.Header)
+		}
+		r.Header.Set(rid.header, id)
+	}
+	return r.WithContext(ContextWithRequestID(r.Context(), id))
+}
+
+func (rid *RequestID) validCharset(id string) bool {
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ContextWithRequestID returns a copy of ctx holding the request ID, for
+// outbound requests made outside of a handler.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request ID held by ctx, or an empty
+// string if it holds none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// RequestIDTransport returns a http.RoundTripper that sets the request ID
+// of the context of outbound requests in their header before passing them
+// to next, so the ID follows the request to other services. If next is nil
+// http.DefaultTransport is used.
+func RequestIDTransport(next http.RoundTripper, config ...RequestIDConfigFunc) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &requestIDTransport{
+		next:      next,
+		requestID: newRequestID(config),
+	}
+}
+
+type requestIDTransport struct {
+	next      http.RoundTripper
+	requestID *RequestID
+}
+
+func (t *requestIDTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	id := RequestIDFromContext(r.Context())
+	if id == "" || r.Header.Get(t.requestID.header) != "" {
+		return t.next.RoundTrip(r)
+	}
+
+	// A RoundTripper must not modify the request.
+	r = r.Clone(r.Context())
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	r.Header.Set(t.requestID.header, id)
+	return t.next.RoundTrip(r)
+}
+
+// UUIDv4 returns a random UUID (version 4), like
+// "3b241101-e2bb-4255-8caf-4136c566a962".
+func UUIDv4() string {
+	b := random(16)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID returns a ULID, a random ID sorting by the time it was made, like
+// "01ARZ3NDEKTSV4RRFFQ69G5FAV".
+func ULID() string {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixNano()/int64(time.Millisecond))<<16)
+	copy(b[6:], random(10))
+
+	n := new(big.Int).SetBytes(b)
+	mask := big.NewInt(31)
+	id := make([]byte, 26)
+	for i := len(id) - 1; i >= 0; i-- {
+		id[i] = crockford[new(big.Int).And(n, mask).Int64()]
+		n.Rsh(n, 5)
+	}
+	return string(id)
+}
+
+func random(n int) []byte {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic("header: can not read random bytes: " + err.Error())
+	}
+	return b
+}
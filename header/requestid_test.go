@@ -0,0 +1,188 @@
+package header
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveRequestID(t *testing.T, hMw *Header, header, id string) (string, string, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	var fromContext, fromHeader string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext = RequestIDFromContext(r.Context())
+		fromHeader = r.Header.Get(header)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	if id != "" {
+		req.Header.Set(header, id)
+	}
+
+	recorder := httptest.NewRecorder()
+	hMw.Wrap(handler).ServeHTTP(recorder, req)
+
+	if req.Header.Get(header) != id {
+		t.Fatal("Unexpected change of the original request:", req.Header)
+	}
+	return fromContext, fromHeader, recorder
+}
+
+func TestRequestID(t *testing.T) {
+	hMw := New(WithRequestID())
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	tests := map[string]bool{
+		"":                                     false,
+		"abc-123_x.y:z":                        true,
+		"bad id":                               false,
+		"bad\nINFO forged log line":            false,
+		strings.Repeat("a", 64):                true,
+		strings.Repeat("a", 65):                false,
+		"3b241101-e2bb-4255-8caf-4136c566a962": true,
+	}
+
+	for incoming, kept := range tests {
+		fromContext, fromHeader, recorder := serveRequestID(t, hMw, "X-Request-ID", incoming)
+
+		if kept && fromContext != incoming {
+			t.Fatal("Unexpected request ID for", incoming, fromContext)
+		}
+		if !kept && !uuid.MatchString(fromContext) {
+			t.Fatal("Unexpected generated request ID for", incoming, fromContext)
+		}
+		if fromHeader != fromContext {
+			t.Fatal("Unexpected request header:", fromHeader)
+		}
+		if echo := recorder.Header().Get("X-Request-ID"); echo != fromContext {
+			t.Fatal("Unexpected response header:", echo)
+		}
+	}
+}
+
+func TestRequestIDConfig(t *testing.T) {
+	hMw := New(WithRequestID(
+		WithRequestIDHeader("X-Correlation-ID"),
+		WithRequestIDGenerator(func() string { return "generated" }),
+		WithRequestIDMaxLength(8),
+		WithRequestIDValidator(func(id string) bool { return strings.HasPrefix(id, "ok") }),
+	))
+
+	tests := map[string]string{
+		"ok-1":      "ok-1",
+		"ok-123456": "generated",
+		"bad":       "generated",
+	}
+
+	for incoming, expected := range tests {
+		fromContext, _, recorder := serveRequestID(t, hMw, "X-Correlation-ID", incoming)
+
+		if fromContext != expected {
+			t.Fatal("Unexpected request ID for", incoming, fromContext)
+		}
+		if echo := recorder.Header().Get("X-Correlation-ID"); echo != expected {
+			t.Fatal("Unexpected response header:", echo)
+		}
+	}
+}
+
+func TestRequestIDWithoutRequest(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	recorder := httptest.NewRecorder()
+	New(WithRequestID()).Wrap(emptyHandler).ServeHTTP(recorder, nil)
+
+	if _, found := recorder.Header()["X-Request-Id"]; found {
+		t.Fatal("Unexpected response header:", recorder.Header())
+	}
+}
+
+func TestULID(t *testing.T) {
+	ulid := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+	first := ULID()
+	time.Sleep(2 * time.Millisecond)
+	second := ULID()
+
+	if !ulid.MatchString(first) || !ulid.MatchString(second) {
+		t.Fatal("Unexpected ULIDs:", first, second)
+	}
+	if first[:10] >= second[:10] {
+		t.Fatal("Unexpected order of ULIDs:", first, second)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+func TestRequestIDTransport(t *testing.T) {
+	var sent string
+	transport := RequestIDTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sent = r.Header.Get("X-Request-ID")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}))
+
+	tests := []struct {
+		contextID string
+		headerID  string
+		expected  string
+	}{
+		{"from-context", "", "from-context"},
+		{"from-context", "from-header", "from-header"},
+		{"", "", ""},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		req = req.WithContext(ContextWithRequestID(context.Background(), test.contextID))
+		if test.headerID != "" {
+			req.Header.Set("X-Request-ID", test.headerID)
+		}
+
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatal("Unexpected error:", err)
+		}
+		if sent != test.expected {
+			t.Fatal("Unexpected request ID sent:", sent)
+		}
+		if req.Header.Get("X-Request-ID") != test.headerID {
+			t.Fatal("Unexpected change of the original request:", req.Header)
+		}
+	}
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	var received string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("X-Request-ID")
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: RequestIDTransport(nil)}
+
+	var id string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = RequestIDFromContext(r.Context())
+		req, _ := http.NewRequest(http.MethodGet, upstream.URL, nil)
+		resp, err := client.Do(req.WithContext(r.Context()))
+		if err != nil {
+			t.Fatal("Unexpected error:", err)
+		}
+		resp.Body.Close()
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	New(WithRequestID(WithRequestIDGenerator(ULID))).Wrap(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	if id == "" || received != id {
+		t.Fatal("Unexpected propagated request ID:", id, received)
+	}
+}
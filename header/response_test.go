@@ -0,0 +1,236 @@
+package header
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func retryAfter(r *http.Request, status int, headers http.Header) (string, string) {
+	if status == http.StatusServiceUnavailable {
+		return "Retry-After", "120"
+	}
+	return "", ""
+}
+
+func htmlPolicy(r *http.Request, status int, headers http.Header) (string, string) {
+	if strings.HasPrefix(headers.Get("Content-Type"), "text/html") {
+		return "Content-Security-Policy", "default-src 'self'"
+	}
+	return "", ""
+}
+
+func TestResponseHeaderFunc(t *testing.T) {
+	hMw := New(WithResponseHeaderFunc(retryAfter), WithResponseHeaderFunc(htmlPolicy))
+
+	tests := []struct {
+		handler http.HandlerFunc
+		retry   string
+		policy  string
+	}{
+		{func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}, "120", ""},
+		{func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<p>hello</p>"))
+		}, "", "default-src 'self'"},
+		{func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		}, "", ""},
+		{func(w http.ResponseWriter, r *http.Request) {}, "", ""},
+	}
+
+	for i, test := range tests {
+		recorder := httptest.NewRecorder()
+		hMw.Wrap(test.handler).ServeHTTP(recorder, nil)
+
+		if retry := recorder.Header().Get("Retry-After"); retry != test.retry {
+			t.Fatal("Unexpected Retry-After for test", i, retry)
+		}
+		if policy := recorder.Header().Get("Content-Security-Policy"); policy != test.policy {
+			t.Fatal("Unexpected Content-Security-Policy for test", i, policy)
+		}
+	}
+}
+
+func TestResponseHeaderFuncCalledOnce(t *testing.T) {
+	calls := 0
+	hMw := New(WithResponseHeaderFunc(func(r *http.Request, status int, headers http.Header) (string, string) {
+		calls++
+		return "X-Status", http.StatusText(status)
+	}))
+
+	h := hMw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("a"))
+		w.Write([]byte("b"))
+	}))
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, nil)
+
+	if calls != 1 {
+		t.Fatal("Unexpected number of calls:", calls)
+	}
+	if status := recorder.Header().Get("X-Status"); status != "Created" {
+		t.Fatal("Unexpected header value:", status)
+	}
+}
+
+// fullWriter implements all the optional interfaces of a ResponseWriter.
+type fullWriter struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+	readFrom bool
+}
+
+func (fw *fullWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	fw.hijacked = true
+	return nil, nil, nil
+}
+
+func (fw *fullWriter) ReadFrom(src io.Reader) (int64, error) {
+	fw.readFrom = true
+	return io.Copy(fw.ResponseRecorder, src)
+}
+
+// plainWriter implements none of the optional interfaces.
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestResponseWriterInterfaces(t *testing.T) {
+	hMw := New(WithResponseHeaderFunc(func(r *http.Request, status int, headers http.Header) (string, string) {
+		return "X-Written", "true"
+	}))
+
+	var flushed, hijacked, readFrom bool
+	h := hMw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, flushed = w.(http.Flusher)
+		_, hijacked = w.(http.Hijacker)
+		_, readFrom = w.(io.ReaderFrom)
+	}))
+
+	h.ServeHTTP(&fullWriter{ResponseRecorder: httptest.NewRecorder()}, nil)
+	if !flushed || !hijacked || !readFrom {
+		t.Fatal("Unexpected missing interfaces:", flushed, hijacked, readFrom)
+	}
+
+	h.ServeHTTP(plainWriter{httptest.NewRecorder()}, nil)
+	if flushed || hijacked || readFrom {
+		t.Fatal("Unexpected interfaces:", flushed, hijacked, readFrom)
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), nil)
+	if !flushed || hijacked || readFrom {
+		t.Fatal("Unexpected interfaces for recorder:", flushed, hijacked, readFrom)
+	}
+}
+
+func TestResponseWriterForwarding(t *testing.T) {
+	hMw := New(WithResponseHeaderFunc(func(r *http.Request, status int, headers http.Header) (string, string) {
+		return "X-Written", "true"
+	}))
+
+	fw := &fullWriter{ResponseRecorder: httptest.NewRecorder()}
+	hMw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(io.ReaderFrom).ReadFrom(strings.NewReader("body"))
+		w.(http.Flusher).Flush()
+	})).ServeHTTP(fw, nil)
+
+	if !fw.readFrom || !fw.Flushed || fw.Body.String() != "body" {
+		t.Fatal("Unexpected forwarding:", fw.readFrom, fw.Flushed, fw.Body.String())
+	}
+	if fw.Header().Get("X-Written") != "true" {
+		t.Fatal("Unexpected missing header")
+	}
+
+	fw = &fullWriter{ResponseRecorder: httptest.NewRecorder()}
+	hMw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Hijacker).Hijack()
+	})).ServeHTTP(fw, nil)
+
+	if !fw.hijacked || fw.Header().Get("X-Written") != "" {
+		t.Fatal("Unexpected hijack forwarding:", fw.hijacked, fw.Header())
+	}
+}
+
+func TestResponseWriterFlushFirst(t *testing.T) {
+	hMw := New(WithResponseHeaderFunc(func(r *http.Request, status int, headers http.Header) (string, string) {
+		return "X-Written", "true"
+	}))
+
+	recorder := httptest.NewRecorder()
+	hMw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+	})).ServeHTTP(recorder, nil)
+
+	if recorder.Header().Get("X-Written") != "true" {
+		t.Fatal("Unexpected missing header after flush")
+	}
+}
+
+// statusWriter records every status code written.
+type statusWriter struct {
+	http.ResponseWriter
+	statuses []int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	sw.statuses = append(sw.statuses, status)
+}
+
+func TestResponseHeaderFuncInformational(t *testing.T) {
+	var seen []int
+	hMw := New(WithResponseHeaderFunc(func(r *http.Request, status int, headers http.Header) (string, string) {
+		seen = append(seen, status)
+		return "X-Status", http.StatusText(status)
+	}))
+
+	sw := &statusWriter{ResponseWriter: httptest.NewRecorder()}
+	hMw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusAccepted)
+	})).ServeHTTP(sw, nil)
+
+	if len(seen) != 1 || seen[0] != http.StatusAccepted {
+		t.Fatal("Unexpected statuses seen:", seen)
+	}
+	if len(sw.statuses) != 2 || sw.statuses[0] != http.StatusEarlyHints || sw.statuses[1] != http.StatusAccepted {
+		t.Fatal("Unexpected statuses written:", sw.statuses)
+	}
+	if status := sw.Header().Get("X-Status"); status != "Accepted" {
+		t.Fatal("Unexpected header value:", status)
+	}
+}
+
+func TestResponseWriterUnwrap(t *testing.T) {
+	hMw := New(WithResponseHeaderFunc(func(r *http.Request, status int, headers http.Header) (string, string) {
+		return "", ""
+	}))
+
+	writers := []http.ResponseWriter{
+		&fullWriter{ResponseRecorder: httptest.NewRecorder()},
+		plainWriter{httptest.NewRecorder()},
+		httptest.NewRecorder(),
+	}
+
+	for _, underlying := range writers {
+		var unwrapped http.ResponseWriter
+		hMw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if u, ok := w.(interface{ Unwrap() http.ResponseWriter }); ok {
+				unwrapped = u.Unwrap()
+			}
+		})).ServeHTTP(underlying, nil)
+
+		if unwrapped != underlying {
+			t.Fatal("Unexpected unwrapped writer:", unwrapped)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package header
+
+import (
+	"net/http"
+)
+
+// Operation is the way a header rule changes the headers.
+type Operation int
+
+const (
+	// Add adds the value to any values the header already has.
+	Add Operation = iota
+	// Set replaces any values the header already has with the value.
+	Set
+	// Delete removes the header, the value is ignored.
+	Delete
+	// SetIfAbsent sets the value only if the header has no values.
+	SetIfAbsent
+)
+
+// Precedence decides whether the headers set by the handler or by the
+// Header win when both set the same header.
+type Precedence int
+
+const (
+	// HandlerPrecedence applies the rules before the handler is called, so
+	// the handler can change the headers. This is the default.
+	HandlerPrecedence Precedence = iota
+	// MiddlewarePrecedence applies the rules just before the response is
+	// written, after the handler has set its headers. The static and
+	// dynamic headers then replace the headers set by the handler.
+	MiddlewarePrecedence
+)
+
+// rule is a header changed by an operation. The header and value are given
+// either directly or by a dynamic or response function.
+type rule struct {
+	op       Operation
+	header   string
+	value    string
+	dynamic  DynamicHeaderFunction
+	response ResponseHeaderFunction
+}
+
+// resolve returns the header and value of a static or dynamic rule.
+func (rl rule) resolve(r *http.Request) (header, value string) {
+	if rl.dynamic != nil {
+		return rl.dynamic(r)
+	}
+	return rl.header, rl.value
+}
+
+// apply changes the headers according to the operation. Nothing is done if
+// the header name is empty.
+func (op Operation) apply(headers http.Header, header, value string) {
+	if header == "" {
+		return
+	}
+
+	switch op {
+	case Add:
+		headers.Add(header, value)
+	case Set:
+		headers.Set(header, value)
+	case Delete:
+		headers.Del(header)
+	case SetIfAbsent:
+		if len(headers.Values(header)) == 0 {
+			headers.Set(header, value)
+		}
+	}
+}
+
+// WithHeaderRule returns a ConfigFunc that changes the header with the
+// given operation, like WithHeaderRule(Delete, "X-Powered-By", "").
+func WithHeaderRule(op Operation, header, value string) ConfigFunc {
+	return func(h *Header) {
+		h.rules = append(h.rules, rule{op: op, header: header, value: value})
+	}
+}
+
+// WithDynamicHeaderRule returns a ConfigFunc that changes the header
+// returned by dFn with the given operation.
+func WithDynamicHeaderRule(op Operation, dFn DynamicHeaderFunction) ConfigFunc {
+	return func(h *Header) {
+		h.rules = append(h.rules, rule{op: op, dynamic: dFn})
+	}
+}
+
+// WithResponseHeaderRule returns a ConfigFunc that changes the header
+// returned by rFn with the given operation when the response is written.
+func WithResponseHeaderRule(op Operation, rFn ResponseHeaderFunction) ConfigFunc {
+	return func(h *Header) {
+		h.responseRules = append(h.responseRules, rule{op: op, response: rFn})
+	}
+}
+
+// WithPrecedence returns a ConfigFunc that configures whether the headers
+// set by the handler or by the Header win on conflict. The default is
+// HandlerPrecedence.
+func WithPrecedence(p Precedence) ConfigFunc {
+	return func(h *Header) {
+		h.precedence = p
+	}
+}
@@ -0,0 +1,174 @@
+package header
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveRules(hMw *Header, handler http.HandlerFunc) http.Header {
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("X-Upstream", "upstream")
+	recorder.Header().Set("X-Powered-By", "upstream")
+	hMw.Wrap(handler).ServeHTTP(recorder, nil)
+	return recorder.Header()
+}
+
+func TestHeaderRules(t *testing.T) {
+	hMw := New(
+		WithHeaderRule(Add, "X-Added", "1"),
+		WithHeaderRule(Add, "X-Added", "2"),
+		WithHeaderRule(Set, "X-Upstream", "middleware"),
+		WithHeaderRule(Delete, "X-Powered-By", ""),
+		WithHeaderRule(SetIfAbsent, "X-Absent", "middleware"),
+		WithHeaderRule(SetIfAbsent, "X-Added", "3"),
+	)
+
+	headers := serveRules(hMw, func(w http.ResponseWriter, r *http.Request) {})
+
+	if added := strings.Join(headers.Values("X-Added"), ","); added != "1,2" {
+		t.Fatal("Unexpected X-Added:", added)
+	}
+	if upstream := strings.Join(headers.Values("X-Upstream"), ","); upstream != "middleware" {
+		t.Fatal("Unexpected X-Upstream:", upstream)
+	}
+	if _, found := headers["X-Powered-By"]; found {
+		t.Fatal("Unexpected X-Powered-By:", headers.Get("X-Powered-By"))
+	}
+	if absent := headers.Get("X-Absent"); absent != "middleware" {
+		t.Fatal("Unexpected X-Absent:", absent)
+	}
+}
+
+func TestDynamicHeaderRule(t *testing.T) {
+	hMw := New(
+		WithDynamicHeaderRule(Set, func(r *http.Request) (string, string) {
+			return "X-Upstream", "dynamic"
+		}),
+		WithDynamicHeaderRule(Delete, func(r *http.Request) (string, string) {
+			return "X-Powered-By", ""
+		}),
+		WithDynamicHeaderRule(Set, func(r *http.Request) (string, string) {
+			return "", "ignored"
+		}),
+	)
+
+	headers := serveRules(hMw, func(w http.ResponseWriter, r *http.Request) {})
+
+	if upstream := strings.Join(headers.Values("X-Upstream"), ","); upstream != "dynamic" {
+		t.Fatal("Unexpected X-Upstream:", upstream)
+	}
+	if _, found := headers["X-Powered-By"]; found {
+		t.Fatal("Unexpected X-Powered-By")
+	}
+	if _, found := headers[""]; found {
+		t.Fatal("Unexpected empty header")
+	}
+}
+
+func TestHandlerPrecedence(t *testing.T) {
+	hMw := New(
+		WithHeaderRule(Set, "Cache-Control", "no-store"),
+		WithHeaderRule(SetIfAbsent, "X-Frame-Options", "DENY"),
+		WithHeaderRule(Delete, "Server", ""),
+	)
+
+	headers := serveRules(hMw, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+		w.Header().Set("Server", "handler")
+	})
+
+	if cache := strings.Join(headers.Values("Cache-Control"), ","); cache != "max-age=60" {
+		t.Fatal("Unexpected Cache-Control:", cache)
+	}
+	if frame := strings.Join(headers.Values("X-Frame-Options"), ","); frame != "SAMEORIGIN" {
+		t.Fatal("Unexpected X-Frame-Options:", frame)
+	}
+	if server := headers.Get("Server"); server != "handler" {
+		t.Fatal("Unexpected Server:", server)
+	}
+}
+
+func TestMiddlewarePrecedenceStatic(t *testing.T) {
+	hMw := New(
+		WithPrecedence(MiddlewarePrecedence),
+		WithStaticHeader("Cache-Control", "no-store"),
+		WithDynamicHeaderFunc(func(r *http.Request) (string, string) {
+			return "X-Frame-Options", "DENY"
+		}),
+	)
+
+	headers := serveRules(hMw, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public")
+		w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+	})
+
+	if cache := strings.Join(headers.Values("Cache-Control"), ","); cache != "no-store" {
+		t.Fatal("Unexpected Cache-Control:", cache)
+	}
+	if frame := strings.Join(headers.Values("X-Frame-Options"), ","); frame != "DENY" {
+		t.Fatal("Unexpected X-Frame-Options:", frame)
+	}
+}
+
+func TestMiddlewarePrecedence(t *testing.T) {
+	hMw := New(
+		WithPrecedence(MiddlewarePrecedence),
+		WithStaticHeader("X-Static", "static"),
+		WithHeaderRule(Set, "Cache-Control", "no-store"),
+		WithHeaderRule(SetIfAbsent, "X-Frame-Options", "DENY"),
+		WithHeaderRule(SetIfAbsent, "X-Content-Type-Options", "nosniff"),
+		WithHeaderRule(Delete, "Server", ""),
+	)
+
+	handlers := map[string]http.HandlerFunc{
+		"write": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+			w.Header().Set("Server", "handler")
+			w.Write([]byte("body"))
+		},
+		"no write": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+			w.Header().Set("Server", "handler")
+		},
+	}
+
+	for name, handler := range handlers {
+		headers := serveRules(hMw, handler)
+
+		if cache := strings.Join(headers.Values("Cache-Control"), ","); cache != "no-store" {
+			t.Fatal("Unexpected Cache-Control for", name, cache)
+		}
+		if frame := strings.Join(headers.Values("X-Frame-Options"), ","); frame != "SAMEORIGIN" {
+			t.Fatal("Unexpected X-Frame-Options for", name, frame)
+		}
+		if options := headers.Get("X-Content-Type-Options"); options != "nosniff" {
+			t.Fatal("Unexpected X-Content-Type-Options for", name, options)
+		}
+		if _, found := headers["Server"]; found {
+			t.Fatal("Unexpected Server for", name)
+		}
+		if static := headers.Get("X-Static"); static != "static" {
+			t.Fatal("Unexpected X-Static for", name, static)
+		}
+	}
+}
+
+func TestResponseHeaderRule(t *testing.T) {
+	hMw := New(WithResponseHeaderRule(SetIfAbsent, func(r *http.Request, status int, headers http.Header) (string, string) {
+		return "Cache-Control", "no-store"
+	}))
+
+	headers := serveRules(hMw, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	if cache := strings.Join(headers.Values("Cache-Control"), ","); cache != "max-age=60" {
+		t.Fatal("Unexpected Cache-Control:", cache)
+	}
+}
@@ -0,0 +1,200 @@
+package header
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type contextKey int
+
+const (
+	nonceKey contextKey = iota
+	requestIDKey
+)
+
+// Security holds the configuration of the security headers added by
+// WithSecurityHeaders. Headers configured with an empty value are left
+// out.
+type Security struct {
+	hstsMaxAge            time.Duration
+	hstsIncludeSubDomains bool
+	hstsPreload           bool
+	frameOptions          string
+	referrerPolicy        string
+	permissionsPolicy     string
+	openerPolicy          string
+	embedderPolicy        string
+	csp                   *CSP
+	cspReportOnly         bool
+}
+
+// SecurityConfigFunc is the type of function used to configure the
+// security headers.
+type SecurityConfigFunc func(*Security)
+
+// WithSecurityHeaders returns a ConfigFunc that sets security headers with
+// secure defaults:
+//
+//	Strict-Transport-Security: max-age=63072000; includeSubDomains
+//	X-Content-Type-Options: nosniff
+//	X-Frame-Options: DENY
+//	Referrer-Policy: no-referrer
+//	Permissions-Policy: camera=(), geolocation=(), microphone=()
+//	Cross-Origin-Opener-Policy: same-origin
+//	Cross-Origin-Embedder-Policy: require-corp
+//	Content-Security-Policy: default-src 'self'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'
+//
+// If the policy has NonceSource a new nonce is made for every request. It
+// is available to the handler through Nonce.
+func WithSecurityHeaders(config ...SecurityConfigFunc) ConfigFunc {
+	s := &Security{
+		hstsMaxAge:            2 * 365 * 24 * time.Hour,
+		hstsIncludeSubDomains: true,
+		frameOptions:          "DENY",
+		referrerPolicy:        "no-referrer",
+		permissionsPolicy:     "camera=(), geolocation=(), microphone=()",
+		openerPolicy:          "same-origin",
+		embedderPolicy:        "require-corp",
+		csp:                   NewCSP().DefaultSrc(Self).ObjectSrc(None).BaseURI(Self).FrameAncestors(None),
+	}
+
+	for _, confFn := range config {
+		confFn(s)
+	}
+
+	return func(h *Header) {
+		if s.hstsMaxAge > 0 {
+			WithHeaderRule(Set, "Strict-Transport-Security", s.hsts())(h)
+		}
+		WithHeaderRule(Set, "X-Content-Type-Options", "nosniff")(h)
+
+		for header, value := range map[string]string{
+			"X-Frame-Options":              s.frameOptions,
+			"Referrer-Policy":              s.referrerPolicy,
+			"Permissions-Policy":           s.permissionsPolicy,
+			"Cross-Origin-Opener-Policy":   s.openerPolicy,
+			"Cross-Origin-Embedder-Policy": s.embedderPolicy,
+		} {
+			if value != "" {
+				WithHeaderRule(Set, header, value)(h)
+			}
+		}
+
+		if s.csp == nil {
+			return
+		}
+
+		cspHeader := "Content-Security-Policy"
+		if s.cspReportOnly {
+			cspHeader = "Content-Security-Policy-Report-Only"
+		}
+
+		if !s.csp.usesNonce() {
+			WithHeaderRule(Set, cspHeader, s.csp.String())(h)
+			return
+		}
+
+		h.prepareFuncs = append(h.prepareFuncs, withNonce)
+		WithDynamicHeaderRule(Set, func(r *http.Request) (string, string) {
+			return cspHeader, s.csp.render(Nonce(r))
+		})(h)
+	}
+}
+
+// WithHSTS returns a SecurityConfigFunc that configures the
+// Strict-Transport-Security header. A maxAge of zero leaves the header out.
+func WithHSTS(maxAge time.Duration, includeSubDomains, preload bool) SecurityConfigFunc {
+	return func(s *Security) {
+		s.hstsMaxAge = maxAge
+		s.hstsIncludeSubDomains = includeSubDomains
+		s.hstsPreload = preload
+	}
+}
+
+// WithFrameOptions returns a SecurityConfigFunc that configures the
+// X-Frame-Options header, like "SAMEORIGIN".
+func WithFrameOptions(value string) SecurityConfigFunc {
+	return func(s *Security) {
+		s.frameOptions = value
+	}
+}
+
+// WithReferrerPolicy returns a SecurityConfigFunc that configures the
+// Referrer-Policy header, like "strict-origin-when-cross-origin".
+func WithReferrerPolicy(value string) SecurityConfigFunc {
+	return func(s *Security) {
+		s.referrerPolicy = value
+	}
+}
+
+// WithPermissionsPolicy returns a SecurityConfigFunc that configures the
+// Permissions-Policy header.
+func WithPermissionsPolicy(value string) SecurityConfigFunc {
+	return func(s *Security) {
+		s.permissionsPolicy = value
+	}
+}
+
+// WithCrossOriginPolicies returns a SecurityConfigFunc that configures the
+// Cross-Origin-Opener-Policy and Cross-Origin-Embedder-Policy headers.
+func WithCrossOriginPolicies(opener, embedder string) SecurityConfigFunc {
+	return func(s *Security) {
+		s.openerPolicy = opener
+		s.embedderPolicy = embedder
+	}
+}
+
+// WithCSP returns a SecurityConfigFunc that configures the
+// Content-Security-Policy. A nil policy leaves the header out.
+func WithCSP(csp *CSP) SecurityConfigFunc {
+	return func(s *Security) {
+		s.csp = csp
+	}
+}
+
+// WithCSPReportOnly returns a SecurityConfigFunc that sends the policy as
+// Content-Security-Policy-Report-Only, so violations are reported but not
+// blocked.
+func WithCSPReportOnly() SecurityConfigFunc {
+	return func(s *Security) {
+		s.cspReportOnly = true
+	}
+}
+
+func (s *Security) hsts() string {
+	value := []string{fmt.Sprintf("max-age=%d", int64(s.hstsMaxAge.Seconds()))}
+	if s.hstsIncludeSubDomains {
+		value = append(value, "includeSubDomains")
+	}
+	if s.hstsPreload {
+		value = append(value, "preload")
+	}
+	return strings.Join(value, "; ")
+}
+
+// Nonce returns the Content-Security-Policy nonce of the request, for use
+// in attributes like <script nonce="...">. An empty string is returned if
+// the request has no nonce.
+func Nonce(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	nonce, _ := r.Context().Value(nonceKey).(string)
+	return nonce
+}
+
+// withNonce returns the request with a new nonce in its context. If no
+// nonce can be made the request has none and the policy allows no nonce.
+func withNonce(r *http.Request) *http.Request {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return r
+	}
+	nonce := base64.StdEncoding.EncodeToString(b)
+	return r.WithContext(context.WithValue(r.Context(), nonceKey, nonce))
+}
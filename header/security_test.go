@@ -0,0 +1,132 @@
+package header
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := New(WithSecurityHeaders()).Wrap(emptyHandler)
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, nil)
+
+	expected := map[string]string{
+		"Strict-Transport-Security":    "max-age=63072000; includeSubDomains",
+		"X-Content-Type-Options":       "nosniff",
+		"X-Frame-Options":              "DENY",
+		"Referrer-Policy":              "no-referrer",
+		"Permissions-Policy":           "camera=(), geolocation=(), microphone=()",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Embedder-Policy": "require-corp",
+		"Content-Security-Policy":      "default-src 'self'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'",
+	}
+
+	for header, value := range expected {
+		if got := recorder.Header().Get(header); got != value {
+			t.Fatal("Unexpected value for", header, got)
+		}
+	}
+}
+
+func TestSecurityHeadersConfig(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := New(WithSecurityHeaders(
+		WithHSTS(time.Hour, false, true),
+		WithFrameOptions("SAMEORIGIN"),
+		WithReferrerPolicy("strict-origin-when-cross-origin"),
+		WithPermissionsPolicy(""),
+		WithCrossOriginPolicies("same-origin-allow-popups", ""),
+		WithCSP(NewCSP().DefaultSrc(Self).ReportURI("/csp")),
+		WithCSPReportOnly(),
+	)).Wrap(emptyHandler)
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, nil)
+
+	expected := map[string]string{
+		"Strict-Transport-Security":           "max-age=3600; preload",
+		"X-Frame-Options":                     "SAMEORIGIN",
+		"Referrer-Policy":                     "strict-origin-when-cross-origin",
+		"Permissions-Policy":                  "",
+		"Cross-Origin-Opener-Policy":          "same-origin-allow-popups",
+		"Cross-Origin-Embedder-Policy":        "",
+		"Content-Security-Policy":             "",
+		"Content-Security-Policy-Report-Only": "default-src 'self'; report-uri /csp",
+	}
+
+	for header, value := range expected {
+		if got := recorder.Header().Get(header); got != value {
+			t.Fatal("Unexpected value for", header, got)
+		}
+	}
+}
+
+func TestSecurityHeadersWithoutHSTS(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := New(WithSecurityHeaders(WithHSTS(0, false, false), WithCSP(nil))).Wrap(emptyHandler)
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, nil)
+
+	for _, header := range []string{"Strict-Transport-Security", "Content-Security-Policy"} {
+		if _, found := recorder.Header()[header]; found {
+			t.Fatal("Unexpected header:", header)
+		}
+	}
+}
+
+func TestCSPNonce(t *testing.T) {
+	var nonces []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonces = append(nonces, Nonce(r))
+	})
+
+	csp := NewCSP().
+		DefaultSrc(Self).
+		ScriptSrc(Self, NonceSource, StrictDynamic).
+		StyleSrc(Self, NonceSource)
+	h := New(WithSecurityHeaders(WithCSP(csp))).Wrap(handler)
+
+	var policies []string
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, req)
+		policies = append(policies, recorder.Header().Get("Content-Security-Policy"))
+	}
+
+	if len(nonces[0]) != 24 || nonces[0] == nonces[1] {
+		t.Fatal("Unexpected nonces:", nonces)
+	}
+
+	for i, policy := range policies {
+		nonce := "'nonce-" + nonces[i] + "'"
+		expected := "default-src 'self'; script-src 'self' " + nonce + " 'strict-dynamic'; style-src 'self' " + nonce
+		if policy != expected {
+			t.Fatal("Unexpected policy:", policy)
+		}
+	}
+}
+
+func TestCSPBuilder(t *testing.T) {
+	csp := NewCSP().
+		DefaultSrc(None).
+		ScriptSrc(Self).
+		Directive("Script-Src", "https://cdn.example.com").
+		ImgSrc(Self, "data:").
+		UpgradeInsecureRequests()
+
+	expected := "default-src 'none'; script-src 'self' https://cdn.example.com; img-src 'self' data:; upgrade-insecure-requests"
+	if csp.String() != expected {
+		t.Fatal("Unexpected policy:", csp.String())
+	}
+
+	if policy := NewCSP().ScriptSrc(NonceSource).String(); strings.Contains(policy, "nonce") {
+		t.Fatal("Unexpected nonce in policy:", policy)
+	}
+}
@@ -0,0 +1,146 @@
+package header
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// responseWriter calls before once, just before the status code and the
+// headers of the response are written, or by finish if the handler wrote
+// nothing.
+type responseWriter struct {
+	http.ResponseWriter
+	before      func(status int)
+	wroteHeader bool
+}
+
+// WriteHeader calls before for the first final status. Informational
+// statuses (1xx), like http.StatusEarlyHints, are passed on as they are
+// followed by the final status.
+func (rw *responseWriter) WriteHeader(status int) {
+	if !rw.wroteHeader && status >= 200 {
+		rw.wroteHeader = true
+		rw.before(status)
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the wrapped writer, so http.ResponseController can reach
+// it.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// finish calls before for handlers that returned without writing, as the
+// server then writes the headers with http.StatusOK.
+func (rw *responseWriter) finish() {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+		rw.before(http.StatusOK)
+	}
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *responseWriter) flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (rw *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	// The connection is taken over, so the headers will never be written.
+	rw.wroteHeader = true
+	return rw.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (rw *responseWriter) readFrom(src io.Reader) (int64, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	return rw.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+}
+
+type flusher struct{ *responseWriter }
+
+func (f flusher) Flush() { f.flush() }
+
+type hijacker struct{ *responseWriter }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) { return h.hijack() }
+
+type readerFrom struct{ *responseWriter }
+
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) { return r.readFrom(src) }
+
+// wrapWriter returns a http.ResponseWriter calling before just before the
+// headers of w are written. The returned writer implements http.Flusher,
+// http.Hijacker and io.ReaderFrom exactly when w does. The finish method of
+// the returned responseWriter must be called when the handler returns.
+func wrapWriter(w http.ResponseWriter, before func(status int)) (http.ResponseWriter, *responseWriter) {
+	rw := &responseWriter{ResponseWriter: w, before: before}
+	return withInterfaces(rw), rw
+}
+
+// withInterfaces returns rw as a writer implementing the optional
+// interfaces of the writer it wraps.
+func withInterfaces(rw *responseWriter) http.ResponseWriter {
+	w := rw.ResponseWriter
+
+	_, isFlusher := w.(http.Flusher)
+	_, isHijacker := w.(http.Hijacker)
+	_, isReaderFrom := w.(io.ReaderFrom)
+
+	switch {
+	case isFlusher && isHijacker && isReaderFrom:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+			readerFrom
+		}{rw, flusher{rw}, hijacker{rw}, readerFrom{rw}}
+	case isFlusher && isHijacker:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+		}{rw, flusher{rw}, hijacker{rw}}
+	case isFlusher && isReaderFrom:
+		return struct {
+			*responseWriter
+			flusher
+			readerFrom
+		}{rw, flusher{rw}, readerFrom{rw}}
+	case isHijacker && isReaderFrom:
+		return struct {
+			*responseWriter
+			hijacker
+			readerFrom
+		}{rw, hijacker{rw}, readerFrom{rw}}
+	case isFlusher:
+		return struct {
+			*responseWriter
+			flusher
+		}{rw, flusher{rw}}
+	case isHijacker:
+		return struct {
+			*responseWriter
+			hijacker
+		}{rw, hijacker{rw}}
+	case isReaderFrom:
+		return struct {
+			*responseWriter
+			readerFrom
+		}{rw, readerFrom{rw}}
+	default:
+		return rw
+	}
+}
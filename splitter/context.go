@@ -0,0 +1,97 @@
+package splitter
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type contextKey int
+
+const (
+	matchKey contextKey = iota
+	subdomainKey
+	versionKey
+	upstreamKey
+)
+
+type param struct {
+	name  string
+	value string
+}
+
+// match holds what the splitters that handled a request have matched.
+type match struct {
+	params    []param
+	matched   string
+	remaining string
+}
+
+func matchFrom(r *http.Request) *match {
+	m, _ := r.Context().Value(matchKey).(*match)
+	if m == nil {
+		return &match{}
+	}
+	return m
+}
+
+// Param returns the value of the named path parameter captured by the
+// splitters that handled the request. If the parameter was not captured
+// an empty string is returned.
+func Param(r *http.Request, name string) string {
+	params := matchFrom(r).params
+	for i := len(params) - 1; i >= 0; i-- {
+		if params[i].name == name {
+			return params[i].value
+		}
+	}
+	return ""
+}
+
+// MatchedPath returns the part of the original request path matched by the
+// splitters that handled the request, including their prefixes.
+func MatchedPath(r *http.Request) string {
+	return matchFrom(r).matched
+}
+
+// RemainingPath returns the part of the original request path that was not
+// matched by the splitters that handled the request. This is also the path
+// of the request passed to the split handler.
+func RemainingPath(r *http.Request) string {
+	return matchFrom(r).remaining
+}
+
+// split returns a shallow copy of r with its own URL for the split handler.
+// The path of the copy is the part of the path not consumed by the split,
+// and the parameters and paths are recorded in the context.
+func (sh *Splitter) split(r *http.Request, raw []string, consumed int, params []param) *http.Request {
+	previous := matchFrom(r)
+
+	u := *r.URL
+	u.RawPath = "/" + strings.Join(raw[consumed:], "/")
+	u.Path = unescape(u.RawPath)
+
+	m := &match{
+		params:    make([]param, 0, len(previous.params)+len(params)),
+		matched:   previous.matched + strings.TrimSuffix(sh.prefix, "/") + unescape("/"+strings.Join(raw[:consumed], "/")),
+		remaining: u.Path,
+	}
+	m.params = append(m.params, previous.params...)
+	m.params = append(m.params, params...)
+
+	sr := r.WithContext(context.WithValue(r.Context(), matchKey, m))
+	sr.URL = &u
+	sr.RequestURI = u.RequestURI()
+	return sr
+}
+
+// unescape returns the unescaped path, or the path itself if it is not
+// correctly escaped.
+func unescape(path string) string {
+	unescaped, err := url.PathUnescape(path)
+	if err != nil {
+		return path
+	}
+	return unescaped
+}
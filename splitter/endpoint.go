@@ -0,0 +1,84 @@
+package splitter
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// endpoint groups the routes that share the same path pattern and hence
+// only differ by method. The first route added is used to match paths.
+type endpoint struct {
+	first  *route
+	routes map[string]*route
+
+	// unrouted holds serveUnrouted wrapped in the middleware of the
+	// Splitter and of each route, by the method of the route.
+	unrouted map[string]http.Handler
+}
+
+func newEndpoint(rt *route) *endpoint {
+	return &endpoint{
+		first:  rt,
+		routes: map[string]*route{rt.method: rt},
+	}
+}
+
+// route returns the route handling the given method. HEAD requests are
+// handled by the GET route unless a HEAD route has been registered.
+func (ep *endpoint) route(method string) *route {
+	if rt, ok := ep.routes[method]; ok {
+		return rt
+	}
+	if method == http.MethodHead {
+		if rt, ok := ep.routes[http.MethodGet]; ok {
+			return rt
+		}
+	}
+	return ep.routes[anyMethod]
+}
+
+// allow returns the value of the Allow header for the endpoint.
+func (ep *endpoint) allow() string {
+	methods := []string{http.MethodOptions}
+	for method := range ep.routes {
+		if method != http.MethodOptions {
+			methods = append(methods, method)
+		}
+	}
+	if _, ok := ep.routes[http.MethodGet]; ok {
+		if _, ok := ep.routes[http.MethodHead]; !ok {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// serveUnrouted answers requests for methods that have no route. OPTIONS
+// requests are answered with the allowed methods, any other method with
+// http.StatusMethodNotAllowed.
+func (ep *endpoint) serveUnrouted(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", ep.allow())
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// unroutedHandler returns the handler answering requests for methods that
+// have no route. It is wrapped in the middleware of the route named by the
+// Access-Control-Request-Method header of preflight requests, so CORS
+// middleware given to that route can answer them, and otherwise in the
+// middleware of the first route.
+func (ep *endpoint) unroutedHandler(r *http.Request) http.Handler {
+	if r.Method == http.MethodOptions {
+		if method := r.Header.Get("Access-Control-Request-Method"); method != "" {
+			if rt := ep.route(method); rt != nil {
+				return ep.unrouted[rt.method]
+			}
+		}
+	}
+	return ep.unrouted[ep.first.method]
+}
@@ -0,0 +1,258 @@
+package splitter
+
+import (
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// FileServer serves files from a http.FileSystem, typically mounted under a
+// split. Unlike http.FileServer it does not list directories unless asked
+// to, it can serve precompressed files and it can serve a single-page app.
+type FileServer struct {
+	fs            http.FileSystem
+	listing       bool
+	precompressed bool
+	spaIndex      string
+}
+
+// FileConfigFunc is the type of function used to configure the FileServer.
+type FileConfigFunc func(*FileServer)
+
+// NewFileServer creates a new FileServer serving the files of fs with the
+// given configuration applied. The file is found by the path of the request
+// which, when mounted with WithSplit, is the path after the split.
+func NewFileServer(fs http.FileSystem, config ...FileConfigFunc) *FileServer {
+	f := &FileServer{
+		fs: fs,
+	}
+
+	for _, confFn := range config {
+		confFn(f)
+	}
+
+	return f
+}
+
+// WithFileSystem returns a ConfigFunc that mounts a FileServer for fs under
+// the given path, so a request for "/static/css/site.css" is served the
+// file "/css/site.css" when the path is "static". The path of the file is
+// available through Param as "file".
+func WithFileSystem(path string, fs http.FileSystem, config ...FileConfigFunc) ConfigFunc {
+	f := NewFileServer(fs, config...)
+	return WithSplit(strings.TrimSuffix(path, "/")+"/{file...}", mountedFiles{f})
+}
+
+// mountedFiles serves the file captured by the catch-all parameter of the
+// split made by WithFileSystem.
+type mountedFiles struct {
+	*FileServer
+}
+
+func (m mountedFiles) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.serveFile(w, r, Param(r, "file"))
+}
+
+// WithDirectoryListing returns a FileConfigFunc that makes the FileServer
+// list the files of directories without an index.html.
+func WithDirectoryListing() FileConfigFunc {
+	return func(f *FileServer) {
+		f.listing = true
+	}
+}
+
+// WithPrecompressed returns a FileConfigFunc that makes the FileServer
+// serve the ".gz" sibling of a file, if there is one, to clients accepting
+// gzip encoding.
+func WithPrecompressed() FileConfigFunc {
+	return func(f *FileServer) {
+		f.precompressed = true
+	}
+}
+
+// WithSPA returns a FileConfigFunc that makes the FileServer serve the given
+// index file, like "/index.html", for paths without a file so a single-page
+// app can route them. Paths with an extension, like "/app.js", are assets
+// and are still answered with NotFound when missing.
+func WithSPA(index string) FileConfigFunc {
+	return func(f *FileServer) {
+		f.spaIndex = path.Clean("/" + index)
+	}
+}
+
+// ServeHTTP serves the file found by the path of the request.
+func (f *FileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.serveFile(w, r, r.URL.Path)
+}
+
+func (f *FileServer) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name = path.Clean("/" + name)
+
+	file, err := f.fs.Open(name)
+	if err != nil {
+		f.serveMissing(w, r, name, err)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		serveFileError(w, r, err)
+		return
+	}
+
+	if info.IsDir() {
+		f.serveDir(w, r, name)
+		return
+	}
+
+	if f.precompressed && f.serveCompressed(w, r, name) {
+		return
+	}
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// serveDir serves the index.html of the directory, the directory listing or
+// the single-page app.
+func (f *FileServer) serveDir(w http.ResponseWriter, r *http.Request, name string) {
+	index := path.Join(name, "index.html")
+	if file, err := f.fs.Open(index); err == nil {
+		defer file.Close()
+		if info, err := file.Stat(); err == nil && !info.IsDir() {
+			http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+			return
+		}
+	}
+
+	if f.listing {
+		f.serveListing(w, r, name)
+		return
+	}
+
+	f.serveMissing(w, r, name, os.ErrNotExist)
+}
+
+// serveListing lists the files of the directory. The links are absolute so
+// they work with and without a trailing slash in the request path.
+func (f *FileServer) serveListing(w http.ResponseWriter, r *http.Request, name string) {
+	dir, err := f.fs.Open(name)
+	if err != nil {
+		serveFileError(w, r, err)
+		return
+	}
+	defer dir.Close()
+
+	infos, err := dir.Readdir(-1)
+	if err != nil {
+		serveFileError(w, r, err)
+		return
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+
+	base := strings.TrimSuffix(MatchedPath(r), "/") + strings.TrimSuffix(r.URL.Path, "/")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<pre>\n")
+	for _, info := range infos {
+		display := info.Name()
+		if info.IsDir() {
+			display += "/"
+		}
+		link := url.URL{Path: base + "/" + display}
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", html.EscapeString(link.String()), html.EscapeString(display))
+	}
+	fmt.Fprintf(w, "</pre>\n")
+}
+
+// serveMissing serves the index of the single-page app for missing
+// non-asset paths and answers with an error otherwise.
+func (f *FileServer) serveMissing(w http.ResponseWriter, r *http.Request, name string, err error) {
+	if f.spaIndex != "" && os.IsNotExist(err) && path.Ext(name) == "" && name != f.spaIndex {
+		f.serveFile(w, r, f.spaIndex)
+		return
+	}
+	serveFileError(w, r, err)
+}
+
+// serveCompressed serves the ".gz" sibling of the file if the client
+// accepts it and reports whether it did.
+func (f *FileServer) serveCompressed(w http.ResponseWriter, r *http.Request, name string) bool {
+	w.Header().Add("Vary", "Accept-Encoding")
+	if !acceptsGzip(r) {
+		return false
+	}
+
+	file, err := f.fs.Open(name + ".gz")
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Encoding", "gzip")
+	http.ServeContent(w, r, path.Base(name), info.ModTime(), file)
+	return true
+}
+
+// acceptsGzip reports whether the Accept-Encoding header of the request
+// allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, entry := range strings.Split(value, ",") {
+			coding := strings.TrimSpace(entry)
+			params := ""
+			if i := strings.Index(coding, ";"); i != -1 {
+				coding, params = strings.TrimSpace(coding[:i]), strings.ReplaceAll(coding[i+1:], " ", "")
+			}
+			if strings.EqualFold(coding, "gzip") && !isZeroQuality(params) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isZeroQuality(params string) bool {
+	return params == "q=0" || params == "q=0.0" || params == "q=0.00" || params == "q=0.000"
+}
+
+// serveFileError answers the request with the status matching the error
+// from opening a file.
+func serveFileError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case os.IsNotExist(err):
+		NotFound(w, r)
+	case os.IsPermission(err):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
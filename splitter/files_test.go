@@ -0,0 +1,213 @@
+package splitter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newFileSystem(t *testing.T, files map[string]string) (http.FileSystem, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "splitter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return http.Dir(dir), func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func serve(h http.Handler, uri string, header ...string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, uri, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestFileSystem(t *testing.T) {
+	fs, cleanup := newFileSystem(t, map[string]string{
+		"css/site.css":    "body {}",
+		"docs/index.html": "docs",
+		"empty/file.txt":  "file",
+	})
+	defer cleanup()
+
+	s := newSplitter(t,
+		WithPrefix("/api"),
+		WithFileSystem("static", fs),
+	)
+
+	tests := []struct {
+		uri    string
+		status int
+		body   string
+	}{
+		{"/api/static/css/site.css", http.StatusOK, "body {}"},
+		{"/api/static/docs", http.StatusOK, "docs"},
+		{"/api/static/docs/", http.StatusOK, "docs"},
+		{"/api/static/empty", http.StatusNotFound, "Not Found\n"},
+		{"/api/static/missing.css", http.StatusNotFound, "Not Found\n"},
+		{"/api/static/../../etc/passwd", http.StatusNotFound, "Not Found\n"},
+	}
+
+	for _, test := range tests {
+		rec := serve(s, test.uri)
+
+		if rec.Code != test.status {
+			t.Fatal("unexpected status for", test.uri, rec.Code)
+		}
+		if rec.Body.String() != test.body {
+			t.Fatal("unexpected body for", test.uri, rec.Body.String())
+		}
+	}
+
+	if contentType := serve(s, "/api/static/css/site.css").Header().Get("Content-Type"); contentType != "text/css; charset=utf-8" {
+		t.Fatal("unexpected content type:", contentType)
+	}
+}
+
+func TestFileServerSplit(t *testing.T) {
+	fs, cleanup := newFileSystem(t, map[string]string{
+		"css/site.css": "body {}",
+	})
+	defer cleanup()
+
+	s := newSplitter(t, WithSplit("assets", NewFileServer(fs)))
+
+	rec := serve(s, "/assets/css/site.css")
+	if rec.Code != http.StatusOK || rec.Body.String() != "body {}" {
+		t.Fatal("unexpected response:", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDirectoryListing(t *testing.T) {
+	fs, cleanup := newFileSystem(t, map[string]string{
+		"files/a.txt":   "a",
+		"files/b/c.txt": "c",
+	})
+	defer cleanup()
+
+	s := newSplitter(t, WithFileSystem("static", fs, WithDirectoryListing()))
+
+	rec := serve(s, "/static/files")
+	if rec.Code != http.StatusOK {
+		t.Fatal("unexpected status:", rec.Code)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `<a href="/static/files/a.txt">a.txt</a>`) || !strings.Contains(body, `<a href="/static/files/b/">b/</a>`) {
+		t.Fatal("unexpected listing:", body)
+	}
+}
+
+func TestPrecompressed(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte("console.log(1)"))
+	zw.Close()
+
+	fs, cleanup := newFileSystem(t, map[string]string{
+		"app.js":    "console.log(1)",
+		"app.js.gz": compressed.String(),
+		"plain.txt": "plain",
+	})
+	defer cleanup()
+
+	s := newSplitter(t, WithFileSystem("/", fs, WithPrecompressed()))
+
+	rec := serve(s, "/app.js", "Accept-Encoding", "br, gzip")
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Body.String() != compressed.String() {
+		t.Fatal("unexpected compressed response:", rec.Header())
+	}
+	if contentType := rec.Header().Get("Content-Type"); !strings.Contains(contentType, "javascript") {
+		t.Fatal("unexpected content type:", contentType)
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatal("unexpected vary:", rec.Header().Get("Vary"))
+	}
+
+	for _, accept := range []string{"", "gzip;q=0", "br"} {
+		rec = serve(s, "/app.js", "Accept-Encoding", accept)
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "console.log(1)" {
+			t.Fatal("unexpected uncompressed response for", accept, rec.Header())
+		}
+	}
+
+	rec = serve(s, "/plain.txt", "Accept-Encoding", "gzip")
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "plain" {
+		t.Fatal("unexpected response without sibling:", rec.Header())
+	}
+}
+
+func TestSPA(t *testing.T) {
+	fs, cleanup := newFileSystem(t, map[string]string{
+		"index.html":     "app",
+		"assets/app.js":  "js",
+		"about/info.txt": "info",
+	})
+	defer cleanup()
+
+	s := newSplitter(t,
+		WithSplit("api", namedHandler("api")),
+		WithFileSystem("/", fs, WithSPA("index.html")),
+	)
+
+	tests := []struct {
+		uri    string
+		status int
+		body   string
+	}{
+		{"/", http.StatusOK, "app"},
+		{"/users/42", http.StatusOK, "app"},
+		{"/about", http.StatusOK, "app"},
+		{"/assets/app.js", http.StatusOK, "js"},
+		{"/assets/missing.js", http.StatusNotFound, "Not Found\n"},
+		{"/about/info.txt", http.StatusOK, "info"},
+	}
+
+	for _, test := range tests {
+		rec := serve(s, test.uri)
+
+		if rec.Code != test.status {
+			t.Fatal("unexpected status for", test.uri, rec.Code)
+		}
+		if rec.Body.String() != test.body {
+			t.Fatal("unexpected body for", test.uri, rec.Body.String())
+		}
+	}
+}
+
+func TestFileServerMethods(t *testing.T) {
+	fs, cleanup := newFileSystem(t, map[string]string{"a.txt": "a"})
+	defer cleanup()
+
+	req, _ := http.NewRequest(http.MethodPost, "/a.txt", nil)
+	rec := httptest.NewRecorder()
+	NewFileServer(fs).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Fatal("unexpected response:", rec.Code, rec.Header())
+	}
+}
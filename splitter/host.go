@@ -0,0 +1,155 @@
+package splitter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// HostSplitter routes requests by their Host before handing them to the
+// handler of the host, typically a Splitter routing by path.
+type HostSplitter struct {
+	hosts          map[string]http.Handler
+	wildcards      []wildcardHost
+	defaultHandler http.Handler
+	err            error
+}
+
+type wildcardHost struct {
+	suffix  string
+	handler http.Handler
+}
+
+// HostConfigFunc is the type of function used to configure the
+// HostSplitter.
+type HostConfigFunc func(*HostSplitter)
+
+// NewHost creates a new HostSplitter with the given configuration applied.
+// An error is returned if a host is given more than once or if the
+// Splitter of a host can not be created.
+func NewHost(config ...HostConfigFunc) (*HostSplitter, error) {
+	hs := &HostSplitter{
+		hosts: make(map[string]http.Handler),
+	}
+
+	for _, confFn := range config {
+		confFn(hs)
+	}
+
+	if hs.err != nil {
+		return nil, hs.err
+	}
+
+	// The most specific wildcard is tried first.
+	sort.SliceStable(hs.wildcards, func(i, j int) bool {
+		return len(hs.wildcards[i].suffix) > len(hs.wildcards[j].suffix)
+	})
+
+	return hs, nil
+}
+
+// WithHost returns a HostConfigFunc that routes requests for the given
+// host to a Splitter created with the given configuration, so the splits
+// and the default handler apply to that host only. The host can be given
+// exactly (like "example.com") or with a wildcard subdomain (like
+// "*.example.com"), in which case the subdomain is available through
+// Subdomain. Ports are ignored.
+func WithHost(host string, config ...ConfigFunc) HostConfigFunc {
+	return func(hs *HostSplitter) {
+		sh, err := NewChecked(config...)
+		if err != nil {
+			hs.setErr(fmt.Errorf("splitter: host %q: %w", host, err))
+			return
+		}
+		WithHostHandler(host, sh)(hs)
+	}
+}
+
+// WithHostHandler returns a HostConfigFunc that routes requests for the
+// given host to h. The host is given as for WithHost.
+func WithHostHandler(host string, h http.Handler) HostConfigFunc {
+	return func(hs *HostSplitter) {
+		name := normalizeHost(host)
+
+		if strings.HasPrefix(name, "*.") {
+			suffix := name[1:]
+			for _, wh := range hs.wildcards {
+				if wh.suffix == suffix {
+					hs.setErr(fmt.Errorf("splitter: host %q given more than once", host))
+					return
+				}
+			}
+			hs.wildcards = append(hs.wildcards, wildcardHost{suffix: suffix, handler: h})
+			return
+		}
+
+		if _, found := hs.hosts[name]; found {
+			hs.setErr(fmt.Errorf("splitter: host %q given more than once", host))
+			return
+		}
+		hs.hosts[name] = h
+	}
+}
+
+// WithDefaultHost returns a HostConfigFunc that routes requests for hosts
+// that are not configured to h. Without a default handler such requests
+// are answered with http.StatusNotFound.
+func WithDefaultHost(h http.Handler) HostConfigFunc {
+	return func(hs *HostSplitter) {
+		hs.defaultHandler = h
+	}
+}
+
+func (hs *HostSplitter) setErr(err error) {
+	if hs.err == nil {
+		hs.err = err
+	}
+}
+
+// ServeHTTP routes the request to the handler of its host. Exact hosts are
+// preferred over wildcard hosts.
+func (hs *HostSplitter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := normalizeHost(r.Host)
+
+	if h, found := hs.hosts[host]; found {
+		h.ServeHTTP(w, r)
+		return
+	}
+
+	for _, wh := range hs.wildcards {
+		if len(host) > len(wh.suffix) && strings.HasSuffix(host, wh.suffix) {
+			subdomain := host[:len(host)-len(wh.suffix)]
+			wh.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), subdomainKey, subdomain)))
+			return
+		}
+	}
+
+	if hs.defaultHandler != nil {
+		hs.defaultHandler.ServeHTTP(w, r)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// Subdomain returns the subdomain matched by the wildcard host of a
+// HostSplitter, like "tenant" for "tenant.example.com" matched by
+// "*.example.com". If the request was not matched by a wildcard host an
+// empty string is returned.
+func Subdomain(r *http.Request) string {
+	subdomain, _ := r.Context().Value(subdomainKey).(string)
+	return subdomain
+}
+
+// normalizeHost lower cases the host and strips the port and any trailing
+// dot.
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
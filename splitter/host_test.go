@@ -0,0 +1,86 @@
+package splitter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostSplitter(t *testing.T) {
+	hit := ""
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hit = name + ":" + Subdomain(r)
+		})
+	}
+
+	hs, err := NewHost(
+		WithHost("api.example.com",
+			WithSplit("users", handler("users")),
+			WithDefaultHandler(handler("api-default")),
+		),
+		WithHostHandler("*.example.com", handler("tenant")),
+		WithHostHandler("*.eu.example.com", handler("eu-tenant")),
+		WithDefaultHost(handler("default")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host, path, hit string
+	}{
+		{"api.example.com", "/users/1", "users:"},
+		{"API.example.com:8080", "/users", "users:"},
+		{"api.example.com.", "/other", "api-default:"},
+		{"acme.example.com", "/", "tenant:acme"},
+		{"a.b.example.com:443", "/", "tenant:a.b"},
+		{"acme.eu.example.com", "/", "eu-tenant:acme"},
+		{"example.com", "/", "default:"},
+		{"other.org", "/", "default:"},
+	}
+
+	for _, test := range tests {
+		hit = ""
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		req.Host = test.host
+		hs.ServeHTTP(httptest.NewRecorder(), req)
+
+		if hit != test.hit {
+			t.Fatal("unexpected handler for", test.host, test.path, hit)
+		}
+	}
+}
+
+func TestHostSplitterNotFound(t *testing.T) {
+	empty := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	hs, err := NewHost(WithHostHandler("example.com", empty))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "other.org"
+	rec := httptest.NewRecorder()
+	hs.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatal("unexpected status code:", rec.Code)
+	}
+}
+
+func TestInvalidHosts(t *testing.T) {
+	empty := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := [][]HostConfigFunc{
+		{WithHostHandler("example.com", empty), WithHostHandler("EXAMPLE.com:80", empty)},
+		{WithHostHandler("*.example.com", empty), WithHostHandler("*.example.com", empty)},
+		{WithHost("example.com", WithSplit("{", empty))},
+	}
+
+	for _, config := range tests {
+		if _, err := NewHost(config...); err == nil {
+			t.Fatal("expected error for invalid hosts")
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package splitter
+
+import (
+	"net/http"
+)
+
+// Middleware is implemented by anything that can wrap a handler, like the
+// middleware of the other packages of this module.
+type Middleware interface {
+	Wrap(http.Handler) http.Handler
+}
+
+// WithMiddleware returns a ConfigFunc that wraps everything the Splitter
+// serves within its prefix in the given middleware: the splits, the default
+// handler, the not found handler and the automatic OPTIONS and
+// http.StatusMethodNotAllowed answers. The middleware is applied in the
+// given order, so the first one given sees the request first. Requests
+// outside the prefix are not wrapped. As nested splitters are served by the
+// splits they inherit the middleware.
+func WithMiddleware(middleware ...Middleware) ConfigFunc {
+	return func(sh *Splitter) {
+		sh.middleware = append(sh.middleware, middleware...)
+	}
+}
+
+// Using returns a SplitOption that wraps the handler of the split in the
+// given middleware, inside the middleware of the Splitter. The middleware
+// also wraps the automatic OPTIONS and http.StatusMethodNotAllowed answers
+// for the path of the split, so CORS middleware can answer preflight
+// requests. The middleware is applied in the given order.
+func Using(middleware ...Middleware) SplitOption {
+	return func(rt *route) {
+		rt.middleware = append(rt.middleware, middleware...)
+	}
+}
+
+// chain wraps h in the middleware so the first one is the outermost.
+func chain(middleware []Middleware, h http.Handler) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i].Wrap(h)
+	}
+	return h
+}
+
+// wrap wraps the handler of the route in the middleware of the route and
+// the Splitter.
+func (sh *Splitter) wrap(rt *route) {
+	rt.serve = chain(sh.middleware, chain(rt.middleware, rt.handler))
+}
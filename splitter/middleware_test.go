@@ -0,0 +1,155 @@
+package splitter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mbanzon/middlex/v4/cors"
+	"github.com/mbanzon/middlex/v4/counting"
+)
+
+// trace is a middleware recording the order it sees requests in.
+type trace struct {
+	name string
+	log  *[]string
+}
+
+func (tr trace) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*tr.log = append(*tr.log, tr.name)
+		h.ServeHTTP(w, r)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	var log []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log = append(log, "handler")
+	})
+
+	admin := newSplitter(t,
+		WithMiddleware(trace{"admin", &log}),
+		WithSplit("users", handler),
+	)
+
+	s := newSplitter(t,
+		WithPrefix("/api"),
+		WithMiddleware(trace{"outer", &log}, trace{"inner", &log}),
+		WithSplit("items", handler, Using(trace{"split 1", &log}, trace{"split 2", &log})),
+		WithSplit("admin", admin, Using(trace{"admin split", &log})),
+		WithMethodSplit(http.MethodGet, "get", handler),
+	)
+
+	tests := map[string]string{
+		"/api/items":       "outer inner split 1 split 2 handler",
+		"/api/admin/users": "outer inner admin split admin handler",
+		"/api/admin/other": "outer inner admin split admin",
+		"/api/missing":     "outer inner",
+		"/other":           "",
+	}
+
+	for uri, expected := range tests {
+		log = nil
+		serve(s, uri)
+
+		if strings.Join(log, " ") != expected {
+			t.Fatal("unexpected order for", uri, log)
+		}
+	}
+
+	log = nil
+	req, _ := http.NewRequest(http.MethodPost, "/api/get", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed || strings.Join(log, " ") != "outer inner" {
+		t.Fatal("unexpected unrouted response:", rec.Code, log)
+	}
+}
+
+func TestMiddlewareRuntime(t *testing.T) {
+	var log []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log = append(log, "handler")
+	})
+
+	s := newSplitter(t, WithMiddleware(trace{"splitter", &log}))
+
+	if err := s.AddSplit("items", handler, Using(trace{"split", &log})); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	s.ReplaceDefault(handler)
+
+	tests := map[string]string{
+		"/items": "splitter split handler",
+		"/other": "splitter handler",
+	}
+
+	for uri, expected := range tests {
+		log = nil
+		serve(s, uri)
+
+		if strings.Join(log, " ") != expected {
+			t.Fatal("unexpected order for", uri, log)
+		}
+	}
+}
+
+func TestMiddlewareRoutes(t *testing.T) {
+	var log []string
+	nested := newSplitter(t, WithSplit("users", namedHandler("users")))
+	s := newSplitter(t, WithSplit("api", nested, Using(trace{"api", &log})))
+
+	routes := s.Routes()
+	if len(routes) != 1 || routes[0].Path != "/api/users" || routes[0].Handler != "users" {
+		t.Fatal("unexpected routes:", routes)
+	}
+}
+
+func TestMiddlewarePackages(t *testing.T) {
+	counter := counting.New()
+	s := newSplitter(t,
+		WithSplit("api", namedHandler("api"), Using(counter)),
+		WithSplit("admin", namedHandler("admin")),
+	)
+
+	serve(s, "/api/users")
+	serve(s, "/admin")
+	serve(s, "/api")
+
+	if counter.Count() != 2 {
+		t.Fatal("unexpected count:", counter.Count())
+	}
+}
+
+func TestMiddlewarePreflight(t *testing.T) {
+	corsMw := cors.New(
+		cors.WithOrigins("https://app.example.com"),
+		cors.WithMethods(http.MethodPut),
+	)
+	var log []string
+	s := newSplitter(t,
+		WithMethodSplit(http.MethodGet, "items/{id}", namedHandler("get"), Using(trace{"get", &log})),
+		WithMethodSplit(http.MethodPut, "items/{id}", namedHandler("put"), Using(corsMw)),
+	)
+
+	req, _ := http.NewRequest(http.MethodOptions, "/items/42", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || len(log) != 0 {
+		t.Fatal("unexpected preflight response:", rec.Code, rec.Header(), log)
+	}
+
+	rec = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, "/items/42", nil)
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed || strings.Join(log, " ") != "get" {
+		t.Fatal("unexpected unrouted response:", rec.Code, log)
+	}
+}
@@ -0,0 +1,113 @@
+package splitter
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WithCleanPath returns a ConfigFunc that makes the Splitter clean the path
+// before routing: duplicate slashes are collapsed and dot segments (also
+// when percent-encoded) are resolved, so "/a//b/../c" is routed as "/a/c".
+// All handlers, including the default and not found handlers, are given a
+// copy of the request with the clean path.
+func WithCleanPath() ConfigFunc {
+	return func(sh *Splitter) {
+		sh.cleanPath = true
+	}
+}
+
+// WithCanonicalRedirect returns a ConfigFunc that makes the Splitter
+// redirect requests within the prefix for non-canonical paths to the
+// canonical path instead of routing them silently. The canonical path has
+// no trailing slash and, with WithCleanPath, is clean. The query is kept.
+// The code should be http.StatusMovedPermanently or
+// http.StatusPermanentRedirect, the latter makes clients keep the method
+// and body of the request.
+func WithCanonicalRedirect(code int) ConfigFunc {
+	return func(sh *Splitter) {
+		sh.redirectCode = code
+	}
+}
+
+// WithRejectEncodedSlash returns a ConfigFunc that makes the Splitter answer
+// requests with percent-encoded path separators ("%2F" and "%5C") with
+// http.StatusBadRequest, for handlers that can not tell them from real
+// separators in r.URL.Path.
+func WithRejectEncodedSlash() ConfigFunc {
+	return func(sh *Splitter) {
+		sh.rejectEncodedSlash = true
+	}
+}
+
+// canonicalPath returns the canonical form of the escaped path.
+func (sh *Splitter) canonicalPath(path string) string {
+	if path == "" {
+		return path
+	}
+
+	canonical := strings.TrimSuffix(path, "/")
+	if sh.cleanPath {
+		canonical = cleanPath(path)
+	}
+	// Leading duplicate slashes are always collapsed, as a redirect to
+	// "//evil.com" would take the client to another host.
+	return "/" + strings.TrimLeft(canonical, "/")
+}
+
+// normalize answers requests with rejected or non-canonical escaped paths
+// and reports whether the request has been answered.
+func (sh *Splitter) normalize(w http.ResponseWriter, r *http.Request, path, canonical string) bool {
+	if sh.rejectEncodedSlash && hasEncodedSlash(path) {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return true
+	}
+
+	if sh.redirectCode != 0 && canonical != path {
+		location := strings.TrimSuffix(matchFrom(r).matched, "/") + canonical
+		if strings.HasPrefix(location, "/\\") {
+			location = "/%5C" + location[2:]
+		}
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, location, sh.redirectCode)
+		return true
+	}
+
+	return false
+}
+
+// withPath returns a copy of the request for the escaped path.
+func withPath(r *http.Request, path string) *http.Request {
+	u := *r.URL
+	u.RawPath = path
+	u.Path = unescape(path)
+
+	cr := r.WithContext(r.Context())
+	cr.URL = &u
+	cr.RequestURI = u.RequestURI()
+	return cr
+}
+
+// cleanPath collapses duplicate slashes, resolves dot segments and removes
+// any trailing slash of the escaped path.
+func cleanPath(path string) string {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		switch unescape(s) {
+		case "", ".":
+		case "..":
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		default:
+			segments = append(segments, s)
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+func hasEncodedSlash(path string) bool {
+	path = strings.ToUpper(path)
+	return strings.Contains(path, "%2F") || strings.Contains(path, "%5C")
+}
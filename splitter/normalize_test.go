@@ -0,0 +1,161 @@
+package splitter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCleanPath(t *testing.T) {
+	var path string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+	})
+
+	s := newSplitter(t, WithCleanPath(), WithSplit("users", handler))
+
+	tests := map[string]string{
+		"/users/1":            "/1",
+		"//users//1/":         "/1",
+		"/users/./1":          "/1",
+		"/users/x/../1":       "/1",
+		"/admin/../users/1":   "/1",
+		"/admin/%2e%2e/users": "/",
+	}
+
+	for requested, expected := range tests {
+		path = ""
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com"+requested, nil)
+		s.ServeHTTP(httptest.NewRecorder(), req)
+
+		if path != expected {
+			t.Fatal("unexpected path for", requested, path)
+		}
+	}
+
+	path = ""
+	req, _ := http.NewRequest(http.MethodGet, "/users/../../admin", nil)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	if path != "" {
+		t.Fatal("request escaping the split routed to it:", path)
+	}
+}
+
+func TestCleanPathDefault(t *testing.T) {
+	var path, uri string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, uri = r.URL.Path, r.RequestURI
+	})
+
+	s := newSplitter(t,
+		WithPrefix("/api"),
+		WithCleanPath(),
+		WithSplit("users", http.NotFoundHandler()),
+		WithDefaultHandler(handler),
+	)
+
+	tests := map[string]string{
+		"/api/x/../b":          "/api/b",
+		"/api/a/%2e%2e/secret": "/api/secret",
+		"/api//b/?q=1":         "/api/b",
+	}
+
+	for requested, expected := range tests {
+		path, uri = "", ""
+		req, _ := http.NewRequest(http.MethodGet, requested, nil)
+		req.RequestURI = requested
+		s.ServeHTTP(httptest.NewRecorder(), req)
+
+		if path != expected || !strings.HasPrefix(uri, expected) {
+			t.Fatal("unexpected path for", requested, path, uri)
+		}
+	}
+}
+
+func TestCanonicalRedirect(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	s := newSplitter(t,
+		WithPrefix("/api"),
+		WithCleanPath(),
+		WithCanonicalRedirect(http.StatusPermanentRedirect),
+		WithSplit("users", handler),
+	)
+
+	tests := map[string]string{
+		"/api/users/":           "/api/users",
+		"/api//users/1?q=a%20b": "/api/users/1?q=a%20b",
+		"/api/x/../users/":      "/api/users",
+	}
+
+	for requested, expected := range tests {
+		req, _ := http.NewRequest(http.MethodPost, requested, nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusPermanentRedirect {
+			t.Fatal("unexpected status code for", requested, rec.Code)
+		}
+
+		if rec.Header().Get("Location") != expected {
+			t.Fatal("unexpected location for", requested, rec.Header().Get("Location"))
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/users/1", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatal("canonical path redirected:", rec.Code)
+	}
+}
+
+func TestCanonicalRedirectOtherHost(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	s := newSplitter(t,
+		WithCanonicalRedirect(http.StatusMovedPermanently),
+		WithDefaultHandler(handler),
+	)
+
+	tests := map[string]string{
+		"//evil.com/":  "/evil.com",
+		"///evil.com/": "/evil.com",
+		"/\\evil.com/": "/%5Cevil.com",
+	}
+
+	for requested, expected := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com"+requested, nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusMovedPermanently {
+			t.Fatal("unexpected status code for", requested, rec.Code)
+		}
+
+		if rec.Header().Get("Location") != expected {
+			t.Fatal("unexpected location for", requested, rec.Header().Get("Location"))
+		}
+	}
+}
+
+func TestRejectEncodedSlash(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request with encoded slash routed")
+	})
+
+	s := newSplitter(t, WithRejectEncodedSlash(), WithSplit("files/{name}", handler))
+
+	for _, path := range []string{"/files/a%2fb", "/files/a%5Cb"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatal("unexpected status code for", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package splitter
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WithNotFoundHandler returns a ConfigFunc that configures the handler for
+// requests that match no split when no default handler is given, and for
+// requests outside the prefix when no fallback handler is given. Without it
+// such requests are answered by NotFound.
+func WithNotFoundHandler(h http.Handler) ConfigFunc {
+	return func(sh *Splitter) {
+		sh.notFoundHandler = h
+	}
+}
+
+// WithFallbackHandler returns a ConfigFunc that passes requests whose path
+// does not start with the prefix to h untouched, which allows splitters with
+// different prefixes to be chained.
+func WithFallbackHandler(h http.Handler) ConfigFunc {
+	return func(sh *Splitter) {
+		sh.fallbackHandler = h
+	}
+}
+
+// NotFound answers the request with http.StatusNotFound. The body is JSON
+// when the client prefers JSON according to the Accept header and plain
+// text otherwise.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	if !prefersJSON(r) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	body, _ := json.Marshal(struct {
+		Status int    `json:"status"`
+		Error  string `json:"error"`
+	}{http.StatusNotFound, http.StatusText(http.StatusNotFound)})
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusNotFound)
+	w.Write(append(body, '\n'))
+}
+
+// serveNotFound answers the request with the configured not found handler.
+func (sh *Splitter) serveNotFound(w http.ResponseWriter, r *http.Request) {
+	if sh.notFoundHandler != nil {
+		sh.notFoundHandler.ServeHTTP(w, r)
+		return
+	}
+	NotFound(w, r)
+}
+
+// prefersJSON reports whether the Accept header of the request ranks a JSON
+// media type at least as high as plain text.
+func prefersJSON(r *http.Request) bool {
+	var jsonQ, textQ float64
+	for _, value := range r.Header.Values("Accept") {
+		for _, entry := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(entry))
+			if err != nil {
+				continue
+			}
+
+			q := 1.0
+			if qValue, found := params["q"]; found {
+				if q, err = strconv.ParseFloat(qValue, 64); err != nil {
+					continue
+				}
+			}
+
+			switch {
+			case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+				if q > jsonQ {
+					jsonQ = q
+				}
+			case mediaType == "text/plain" || mediaType == "text/*" || mediaType == "*/*":
+				if q > textQ {
+					textQ = q
+				}
+			}
+		}
+	}
+	return jsonQ > 0 && jsonQ >= textQ
+}
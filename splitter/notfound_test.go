@@ -0,0 +1,190 @@
+package splitter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotFound(t *testing.T) {
+	s := newSplitter(t,
+		WithPrefix("/api"),
+		WithSplit("users", namedHandler("users")),
+	)
+
+	tests := map[string]string{
+		"":                                    "text/plain; charset=utf-8",
+		"text/html, */*;q=0.8":                "text/plain; charset=utf-8",
+		"application/json":                    "application/json; charset=utf-8",
+		"application/problem+json, */*;q=0.1": "application/json; charset=utf-8",
+		"text/plain, application/json;q=0.5":  "text/plain; charset=utf-8",
+	}
+
+	for _, uri := range []string{"/api/orders", "/other"} {
+		for accept, expected := range tests {
+			req, _ := http.NewRequest(http.MethodGet, uri, nil)
+			if accept != "" {
+				req.Header.Set("Accept", accept)
+			}
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNotFound {
+				t.Fatal("unexpected status:", rec.Code)
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != expected {
+				t.Fatal("unexpected content type:", contentType)
+			}
+		}
+	}
+}
+
+func TestNotFoundJSON(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json")
+
+	rec := httptest.NewRecorder()
+	NotFound(rec, req)
+
+	var body struct {
+		Status int
+		Error  string
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if body.Status != http.StatusNotFound || body.Error != "Not Found" {
+		t.Fatal("unexpected body:", body)
+	}
+}
+
+func TestNotFoundHandler(t *testing.T) {
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+
+	s := newSplitter(t,
+		WithPrefix("/api"),
+		WithSplit("users", namedHandler("users")),
+		WithNotFoundHandler(notFound),
+	)
+
+	for _, uri := range []string{"/api/orders", "/other"} {
+		req, _ := http.NewRequest(http.MethodGet, uri, nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusGone {
+			t.Fatal("unexpected status:", rec.Code)
+		}
+	}
+}
+
+func TestFallbackHandler(t *testing.T) {
+	var hit string
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hit = name + " " + r.URL.Path
+		})
+	}
+
+	web := newSplitter(t,
+		WithPrefix("/web"),
+		WithDefaultHandler(handler("web")),
+	)
+	api := newSplitter(t,
+		WithPrefix("/api"),
+		WithSplit("users", handler("api")),
+		WithFallbackHandler(web),
+	)
+
+	tests := map[string]string{
+		"/api/users": "api /",
+		"/web/index": "web /web/index",
+	}
+
+	for uri, expected := range tests {
+		hit = ""
+		req, _ := http.NewRequest(http.MethodGet, uri, nil)
+		api.ServeHTTP(httptest.NewRecorder(), req)
+
+		if hit != expected {
+			t.Fatal("unexpected handler for", uri, hit)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/other", nil)
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "Not Found\n" {
+		t.Fatal("unexpected response:", rec.Code, rec.Body.String())
+	}
+}
+
+func TestFallbackPrefixBoundary(t *testing.T) {
+	var hit string
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hit = name + " " + r.URL.Path
+		})
+	}
+
+	apiary := newSplitter(t,
+		WithPrefix("/apiary"),
+		WithSplit("bees", handler("apiary")),
+	)
+	api := newSplitter(t,
+		WithPrefix("/api"),
+		WithSplit("bees", handler("api")),
+		WithDefaultHandler(handler("api default")),
+		WithFallbackHandler(apiary),
+	)
+
+	tests := map[string]string{
+		"/api":         "api default /api",
+		"/api/":        "api default /api/",
+		"/api/bees":    "api /",
+		"/apiary/bees": "apiary /",
+	}
+
+	for uri, expected := range tests {
+		hit = ""
+		req, _ := http.NewRequest(http.MethodGet, uri, nil)
+		api.ServeHTTP(httptest.NewRecorder(), req)
+
+		if hit != expected {
+			t.Fatal("unexpected handler for", uri, hit)
+		}
+	}
+}
+
+func TestFallbackBeforeRedirect(t *testing.T) {
+	var hit string
+	fallback := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = r.URL.Path
+	})
+
+	s := newSplitter(t,
+		WithPrefix("/api"),
+		WithSplit("bees", http.NotFoundHandler()),
+		WithCanonicalRedirect(http.StatusMovedPermanently),
+		WithRejectEncodedSlash(),
+		WithFallbackHandler(fallback),
+	)
+
+	for _, uri := range []string{"/other/", "/other%2Fx"} {
+		hit = ""
+		rec := serve(s, uri)
+		if rec.Code != http.StatusOK || hit == "" {
+			t.Fatal("unexpected response for", uri, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+
+	rec := serve(s, "/api/bees/")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/api/bees" {
+		t.Fatal("unexpected redirect:", rec.Code, rec.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,140 @@
+package splitter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type segmentKind int
+
+const (
+	staticSegment segmentKind = iota
+	paramSegment
+	catchAllSegment
+)
+
+// segment is a single part of a split pattern. For static segments value
+// is the literal to match, for parameter segments it is the name of the
+// parameter.
+type segment struct {
+	kind  segmentKind
+	value string
+}
+
+const (
+	// anyMethod is the method of routes that handle all methods.
+	anyMethod = ""
+)
+
+// route is a compiled split.
+type route struct {
+	pattern  string
+	method   string
+	name     string
+	segments []segment
+	handler  http.Handler
+
+	// serve is the handler wrapped in the middleware of the route and the
+	// Splitter.
+	middleware []Middleware
+	serve      http.Handler
+}
+
+// SplitOption is the type of function used to configure a single split.
+type SplitOption func(*route)
+
+// Named returns a SplitOption that names the split so URLs for it can be
+// built with URL.
+func Named(name string) SplitOption {
+	return func(rt *route) {
+		rt.name = name
+	}
+}
+
+func newRoute(method, pattern string, h http.Handler, opts []SplitOption) *route {
+	rt := &route{pattern: pattern, method: method, handler: h}
+	for _, opt := range opts {
+		opt(rt)
+	}
+	return rt
+}
+
+// parsePattern compiles a split pattern like "users/{id}/orders" or
+// "files/{path...}" into its segments.
+func parsePattern(pattern string) ([]segment, error) {
+	parts := splitPath(pattern)
+	segments := make([]segment, 0, len(parts))
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") && !strings.HasSuffix(part, "}") {
+			if strings.ContainsAny(part, "{}") {
+				return nil, fmt.Errorf("splitter: invalid segment %q in pattern %q", part, pattern)
+			}
+			segments = append(segments, segment{kind: staticSegment, value: part})
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(part, "{"), "}")
+		if len(name) != len(part)-2 || name == "" || strings.ContainsAny(name, "{}") {
+			return nil, fmt.Errorf("splitter: invalid parameter %q in pattern %q", part, pattern)
+		}
+
+		if strings.HasSuffix(name, "...") {
+			name = strings.TrimSuffix(name, "...")
+			if name == "" {
+				return nil, fmt.Errorf("splitter: invalid parameter %q in pattern %q", part, pattern)
+			}
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("splitter: catch-all parameter %q must be last in pattern %q", part, pattern)
+			}
+			segments = append(segments, segment{kind: catchAllSegment, value: name})
+			continue
+		}
+
+		segments = append(segments, segment{kind: paramSegment, value: name})
+	}
+
+	return segments, nil
+}
+
+// match tries to match the route against the path segments. On success
+// the captured parameters and the segments not consumed by the route are
+// returned.
+func (rt *route) match(segments []string) (ok bool, params []param, rest []string) {
+	if len(rt.segments) == 0 {
+		return len(segments) == 0, nil, nil
+	}
+
+	for i, s := range rt.segments {
+		if s.kind == catchAllSegment {
+			params = append(params, param{name: s.value, value: strings.Join(segments[i:], "/")})
+			return true, params, nil
+		}
+
+		if i >= len(segments) {
+			return false, nil, nil
+		}
+
+		switch s.kind {
+		case staticSegment:
+			if segments[i] != s.value {
+				return false, nil, nil
+			}
+		case paramSegment:
+			params = append(params, param{name: s.value, value: segments[i]})
+		}
+	}
+
+	return true, params, segments[len(rt.segments):]
+}
+
+// splitPath splits the path into its non-empty segments.
+func splitPath(path string) []string {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
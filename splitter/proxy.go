@@ -0,0 +1,325 @@
+package splitter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Proxy forwards requests to one or more upstream servers using a
+// httputil.ReverseProxy. The path of the request, which is the path after
+// the split when mounted with WithSplit, is appended to the path of the
+// upstream. Requests are distributed round-robin unless
+// WithLeastConnections is given, and upstreams failing repeatedly are
+// ejected for a while when WithPassiveHealth is given.
+type Proxy struct {
+	upstreams []*upstream
+	proxy     *httputil.ReverseProxy
+	timeout   time.Duration
+
+	leastConnections bool
+	maxFails         int
+	ejectFor         time.Duration
+
+	mutex *sync.Mutex
+	next  int
+	now   func() time.Time
+	err   error
+}
+
+type upstream struct {
+	url          *url.URL
+	timeout      time.Duration
+	active       int
+	fails        int
+	ejectedUntil time.Time
+}
+
+// ProxyConfigFunc is the type of function used to configure the Proxy.
+type ProxyConfigFunc func(*Proxy)
+
+// NewProxy creates a new Proxy forwarding to the given upstream URL with the
+// given configuration applied. An error is returned if an upstream URL is
+// invalid.
+func NewProxy(targetURL string, config ...ProxyConfigFunc) (*Proxy, error) {
+	p := &Proxy{
+		mutex: &sync.Mutex{},
+		now:   time.Now,
+	}
+	p.proxy = &httputil.ReverseProxy{
+		Director:       p.direct,
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler:   p.handleError,
+	}
+
+	WithUpstream(targetURL, 0)(p)
+	for _, confFn := range config {
+		confFn(p)
+	}
+
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	return p, nil
+}
+
+// WithProxySplit returns a ConfigFunc that routes requests matching the
+// given path to a Proxy forwarding to the given upstream URL. The matched
+// path is stripped, so with the path "api" and the upstream
+// "http://backend/v1" a request for "/api/users" is forwarded to
+// "http://backend/v1/users".
+func WithProxySplit(path, targetURL string, config ...ProxyConfigFunc) ConfigFunc {
+	return func(sh *Splitter) {
+		p, err := NewProxy(targetURL, config...)
+		if err != nil {
+			sh.setErr(err)
+			return
+		}
+		WithSplit(path, p)(sh)
+	}
+}
+
+// WithUpstream returns a ProxyConfigFunc that adds an upstream to forward
+// requests to. Requests to the upstream are cancelled after the given
+// timeout, if it is not zero the timeout given by WithProxyTimeout is
+// overridden.
+func WithUpstream(targetURL string, timeout time.Duration) ProxyConfigFunc {
+	return func(p *Proxy) {
+		u, err := url.Parse(targetURL)
+		if err != nil {
+			p.setErr(fmt.Errorf("splitter: invalid upstream %q: %v", targetURL, err))
+			return
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			p.setErr(fmt.Errorf("splitter: invalid upstream %q: an absolute http or https URL is required", targetURL))
+			return
+		}
+		p.upstreams = append(p.upstreams, &upstream{url: u, timeout: timeout})
+	}
+}
+
+// WithProxyTimeout returns a ProxyConfigFunc that cancels requests to
+// upstreams without their own timeout after the given duration.
+func WithProxyTimeout(timeout time.Duration) ProxyConfigFunc {
+	return func(p *Proxy) {
+		p.timeout = timeout
+	}
+}
+
+// WithLeastConnections returns a ProxyConfigFunc that makes the Proxy
+// forward requests to the upstream with the fewest requests in flight
+// instead of distributing them round-robin.
+func WithLeastConnections() ProxyConfigFunc {
+	return func(p *Proxy) {
+		p.leastConnections = true
+	}
+}
+
+// WithPassiveHealth returns a ProxyConfigFunc that ejects an upstream for
+// the given duration when maxFails requests in a row have failed, either
+// because the upstream could not be reached or because it answered with
+// http.StatusBadGateway, http.StatusServiceUnavailable or
+// http.StatusGatewayTimeout. If all upstreams are ejected they are all used
+// again.
+func WithPassiveHealth(maxFails int, ejectFor time.Duration) ProxyConfigFunc {
+	return func(p *Proxy) {
+		p.maxFails = maxFails
+		p.ejectFor = ejectFor
+	}
+}
+
+// WithTransport returns a ProxyConfigFunc that configures the
+// http.RoundTripper used to forward requests. The default is
+// http.DefaultTransport.
+func WithTransport(transport http.RoundTripper) ProxyConfigFunc {
+	return func(p *Proxy) {
+		p.proxy.Transport = transport
+	}
+}
+
+func (p *Proxy) setErr(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// String describes the proxy by its upstreams.
+func (p *Proxy) String() string {
+	urls := make([]string, len(p.upstreams))
+	for i, u := range p.upstreams {
+		urls[i] = u.url.String()
+	}
+	return "proxy to " + strings.Join(urls, ", ")
+}
+
+// ServeHTTP forwards the request to one of the upstreams.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u := p.pick()
+	defer p.done(u)
+
+	ctx := context.WithValue(r.Context(), upstreamKey, u)
+	timeout := u.timeout
+	if timeout == 0 {
+		timeout = p.timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	p.proxy.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// pick chooses the upstream for a request and counts it as active.
+func (p *Proxy) pick() *upstream {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := p.now()
+	var healthy []*upstream
+	for _, u := range p.upstreams {
+		if !now.Before(u.ejectedUntil) {
+			healthy = append(healthy, u)
+		}
+	}
+	if len(healthy) == 0 {
+		healthy = p.upstreams
+	}
+
+	var chosen *upstream
+	if p.leastConnections {
+		for _, u := range healthy {
+			if chosen == nil || u.active < chosen.active {
+				chosen = u
+			}
+		}
+	} else {
+		chosen = healthy[p.next%len(healthy)]
+		p.next++
+	}
+
+	chosen.active++
+	return chosen
+}
+
+func (p *Proxy) done(u *upstream) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	u.active--
+}
+
+// report records the outcome of a request to the upstream and ejects it
+// when it has failed too many times in a row.
+func (p *Proxy) report(u *upstream, failed bool) {
+	if p.maxFails <= 0 {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !failed {
+		u.fails = 0
+		return
+	}
+
+	u.fails++
+	if u.fails >= p.maxFails {
+		u.fails = 0
+		u.ejectedUntil = p.now().Add(p.ejectFor)
+	}
+}
+
+// direct rewrites the outgoing request for the upstream chosen for it.
+func (p *Proxy) direct(r *http.Request) {
+	u := r.Context().Value(upstreamKey).(*upstream)
+	target := u.url
+
+	escaped := r.URL.EscapedPath()
+	r.URL.Scheme = target.Scheme
+	r.URL.Host = target.Host
+	r.URL.Path = joinPath(target.Path, r.URL.Path)
+	r.URL.RawPath = joinPath(target.EscapedPath(), escaped)
+	if target.RawQuery == "" || r.URL.RawQuery == "" {
+		r.URL.RawQuery = target.RawQuery + r.URL.RawQuery
+	} else {
+		r.URL.RawQuery = target.RawQuery + "&" + r.URL.RawQuery
+	}
+
+	proto := "http"
+	if r.TLS != nil {
+		proto = "https"
+	}
+	r.Header.Set("X-Forwarded-Host", r.Host)
+	r.Header.Set("X-Forwarded-Proto", proto)
+	if prefix := MatchedPath(r); prefix != "" {
+		r.Header.Set("X-Forwarded-Prefix", prefix)
+	}
+
+	forwarded := fmt.Sprintf("host=%q;proto=%s", r.Host, proto)
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if strings.Contains(ip, ":") {
+			ip = fmt.Sprintf("%q", "["+ip+"]")
+		}
+		forwarded = "for=" + ip + ";" + forwarded
+	}
+	if prior := r.Header.Get("Forwarded"); prior != "" {
+		forwarded = prior + ", " + forwarded
+	}
+	r.Header.Set("Forwarded", forwarded)
+
+	r.Host = target.Host
+}
+
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	u := resp.Request.Context().Value(upstreamKey).(*upstream)
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		p.report(u, true)
+	default:
+		p.report(u, false)
+	}
+	return nil
+}
+
+// handleError answers requests that could not be forwarded with
+// http.StatusGatewayTimeout if the upstream timed out and
+// http.StatusBadGateway otherwise.
+func (p *Proxy) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(r.Context().Err(), context.Canceled) {
+		// The client went away, the upstream is not to blame.
+		return
+	}
+
+	p.report(r.Context().Value(upstreamKey).(*upstream), true)
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+}
+
+// joinPath joins the path of the upstream with the path of the request.
+func joinPath(base, path string) string {
+	switch {
+	case path == "" || path == "/":
+		if base == "" {
+			return "/"
+		}
+		return base
+	case strings.HasSuffix(base, "/"):
+		return base + strings.TrimPrefix(path, "/")
+	default:
+		return base + "/" + strings.TrimPrefix(path, "/")
+	}
+}
@@ -0,0 +1,218 @@
+package splitter
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newUpstream(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream", name)
+		w.Header().Set("X-Path", r.URL.RequestURI())
+		w.Header().Set("X-Host", r.Host)
+		for _, header := range []string{"X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto", "X-Forwarded-Prefix", "Forwarded"} {
+			w.Header().Set("X-Got-"+header, r.Header.Get(header))
+		}
+	}))
+}
+
+func newProxy(t *testing.T, targetURL string, config ...ProxyConfigFunc) *Proxy {
+	t.Helper()
+
+	p, err := NewProxy(targetURL, config...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestProxySplit(t *testing.T) {
+	backend := newUpstream(t, "backend")
+	defer backend.Close()
+
+	s := newSplitter(t,
+		WithPrefix("/api"),
+		WithProxySplit("users", backend.URL+"/v1?key=1"),
+	)
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/users/42/a%2Fb?page=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if path := resp.Header.Get("X-Path"); path != "/v1/42/a%2Fb?key=1&page=2" {
+		t.Fatal("unexpected path:", path)
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	if got := resp.Header.Get("X-Host"); got != strings.TrimPrefix(backend.URL, "http://") {
+		t.Fatal("unexpected host:", got)
+	}
+
+	expected := map[string]string{
+		"X-Forwarded-For":    "127.0.0.1",
+		"X-Forwarded-Host":   host,
+		"X-Forwarded-Proto":  "http",
+		"X-Forwarded-Prefix": "/api/users",
+		"Forwarded":          `for=127.0.0.1;host="` + host + `";proto=http`,
+	}
+	for header, value := range expected {
+		if got := resp.Header.Get("X-Got-" + header); got != value {
+			t.Fatal("unexpected", header, got)
+		}
+	}
+}
+
+func TestProxyInvalidUpstream(t *testing.T) {
+	for _, target := range []string{"backend", "ftp://backend", "http://%zz"} {
+		if _, err := NewChecked(WithProxySplit("api", target)); err == nil {
+			t.Fatal("expected error for upstream", target)
+		}
+	}
+}
+
+func TestProxyRoundRobin(t *testing.T) {
+	a := newUpstream(t, "a")
+	defer a.Close()
+	b := newUpstream(t, "b")
+	defer b.Close()
+
+	p := newProxy(t, a.URL, WithUpstream(b.URL, 0))
+
+	var hits []string
+	for i := 0; i < 4; i++ {
+		rec := serve(p, "/")
+		hits = append(hits, rec.Header().Get("X-Upstream"))
+	}
+
+	if strings.Join(hits, "") != "abab" {
+		t.Fatal("unexpected distribution:", hits)
+	}
+}
+
+func TestProxyLeastConnections(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Header().Set("X-Upstream", "slow")
+	}))
+	defer slow.Close()
+	fast := newUpstream(t, "fast")
+	defer fast.Close()
+
+	p := newProxy(t, slow.URL, WithUpstream(fast.URL, 0), WithLeastConnections())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve(p, "/")
+	}()
+	<-started
+
+	for i := 0; i < 3; i++ {
+		if upstream := serve(p, "/").Header().Get("X-Upstream"); upstream != "fast" {
+			t.Fatal("unexpected upstream:", upstream)
+		}
+	}
+
+	close(release)
+	wg.Wait()
+}
+
+func TestProxyPassiveHealth(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream", "failing")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthy := newUpstream(t, "healthy")
+	defer healthy.Close()
+
+	now := time.Now()
+	p := newProxy(t, failing.URL, WithUpstream(healthy.URL, 0), WithPassiveHealth(2, time.Minute))
+	p.now = func() time.Time { return now }
+
+	var hits []string
+	for i := 0; i < 8; i++ {
+		hits = append(hits, serve(p, "/").Header().Get("X-Upstream"))
+	}
+
+	failures := 0
+	for _, hit := range hits {
+		if hit == "failing" {
+			failures++
+		}
+	}
+	if failures != 2 {
+		t.Fatal("unexpected requests to failing upstream:", hits)
+	}
+
+	now = now.Add(2 * time.Minute)
+	found := false
+	for i := 0; i < 2; i++ {
+		if serve(p, "/").Header().Get("X-Upstream") == "failing" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected failing upstream to be used again")
+	}
+}
+
+func TestProxyUnreachable(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	p := newProxy(t, down.URL, WithPassiveHealth(1, time.Minute))
+	if rec := serve(p, "/"); rec.Code != http.StatusBadGateway {
+		t.Fatal("unexpected status:", rec.Code)
+	}
+}
+
+func TestProxyTimeout(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		ioutil.ReadAll(r.Body)
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	fast := newUpstream(t, "fast")
+	defer fast.Close()
+
+	p := newProxy(t, slow.URL, WithProxyTimeout(20*time.Millisecond), WithUpstream(fast.URL, time.Second))
+
+	if rec := serve(p, "/"); rec.Code != http.StatusGatewayTimeout {
+		t.Fatal("unexpected status:", rec.Code)
+	}
+	if rec := serve(p, "/"); rec.Code != http.StatusOK {
+		t.Fatal("unexpected status:", rec.Code)
+	}
+}
+
+func TestProxyRoutes(t *testing.T) {
+	s := newSplitter(t, WithProxySplit("api", "http://backend:8080"))
+
+	routes := s.Routes()
+	if len(routes) != 1 || routes[0].Handler != "proxy to http://backend:8080" {
+		t.Fatal("unexpected routes:", routes)
+	}
+}
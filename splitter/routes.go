@@ -0,0 +1,235 @@
+package splitter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Route describes a split of a Splitter.
+type Route struct {
+	// Path is the full path of the split including the prefix and the
+	// paths of the splits of any parent splitters, like "/api/users/{id}".
+	Path string
+	// Method is the method of the split, an empty string for splits
+	// handling any method.
+	Method string
+	// Handler describes the handler of the split.
+	Handler string
+}
+
+// Routes returns the splits of the Splitter sorted by path and method.
+// Splits to another Splitter are replaced by the splits of that Splitter.
+func (sh *Splitter) Routes() []Route {
+	routes := sh.routesFrom("")
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+func (sh *Splitter) routesFrom(parent string) []Route {
+	t := sh.table.Load().(*table)
+	base := parent + strings.TrimSuffix(sh.prefix, "/")
+
+	var routes []Route
+	for _, rt := range t.routes {
+		path := base + formatSegments(rt.segments)
+		if len(rt.segments) == 0 && base != "" {
+			path = base
+		}
+		if nested, ok := rt.handler.(*Splitter); ok {
+			routes = append(routes, nested.routesFrom(strings.TrimSuffix(path, "/"))...)
+			continue
+		}
+		routes = append(routes, Route{
+			Path:    path,
+			Method:  rt.method,
+			Handler: describeHandler(rt.handler),
+		})
+	}
+	return routes
+}
+
+// formatSegments returns the canonical form of a pattern.
+func formatSegments(segments []segment) string {
+	if len(segments) == 0 {
+		return "/"
+	}
+
+	var b strings.Builder
+	for _, s := range segments {
+		b.WriteString("/")
+		switch s.kind {
+		case staticSegment:
+			b.WriteString(s.value)
+		case paramSegment:
+			b.WriteString("{" + s.value + "}")
+		case catchAllSegment:
+			b.WriteString("{" + s.value + "...}")
+		}
+	}
+	return b.String()
+}
+
+// describeHandler describes the handler by its String method if it has
+// one, by its function name if it is a function or else by its type.
+func describeHandler(h http.Handler) string {
+	if s, ok := h.(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	if fn, ok := h.(http.HandlerFunc); ok {
+		if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+			return f.Name()
+		}
+	}
+
+	return fmt.Sprintf("%T", h)
+}
+
+// FormatRoutes renders the routes as a plain text table.
+func FormatRoutes(routes []Route) string {
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER")
+	for _, r := range routes {
+		method := r.Method
+		if method == anyMethod {
+			method = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", method, r.Path, r.Handler)
+	}
+	tw.Flush()
+	return b.String()
+}
+
+var (
+	// openAPIMethods are the methods an OpenAPI path item can describe,
+	// the first five are used for splits handling any method.
+	openAPIMethods = []string{
+		http.MethodGet,
+		http.MethodPut,
+		http.MethodPost,
+		http.MethodDelete,
+		http.MethodPatch,
+		http.MethodHead,
+		http.MethodOptions,
+		http.MethodTrace,
+	}
+)
+
+type openAPIDocument struct {
+	OpenAPI string                                 `json:"openapi"`
+	Info    openAPIInfo                            `json:"info"`
+	Paths   map[string]map[string]openAPIOperation `json:"paths"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIOperation struct {
+	Summary    string                     `json:"summary,omitempty"`
+	Parameters []openAPIParameter         `json:"parameters,omitempty"`
+	Responses  map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string            `json:"name"`
+	In       string            `json:"in"`
+	Required bool              `json:"required"`
+	Schema   map[string]string `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Description string `json:"description"`
+}
+
+// OpenAPI renders the routes as a minimal OpenAPI 3 document with an
+// operation for each method of each path, to be used as a skeleton for the
+// API documentation. Routes handling any method get an operation for each
+// of GET, PUT, POST, DELETE and PATCH unless the path has a route for the
+// method.
+func OpenAPI(routes []Route, title, version string) ([]byte, error) {
+	doc := openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: title, Version: version},
+		Paths:   make(map[string]map[string]openAPIOperation),
+	}
+
+	for _, r := range routes {
+		segments, err := parsePattern(r.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		var parameters []openAPIParameter
+		path := ""
+		for _, s := range segments {
+			if s.kind == staticSegment {
+				path += "/" + s.value
+				continue
+			}
+			path += "/{" + s.value + "}"
+			parameters = append(parameters, openAPIParameter{
+				Name:     s.value,
+				In:       "path",
+				Required: true,
+				Schema:   map[string]string{"type": "string"},
+			})
+		}
+		if path == "" {
+			path = "/"
+		}
+
+		methods := []string{r.Method}
+		if r.Method == anyMethod {
+			methods = openAPIMethods[:5]
+		}
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(map[string]openAPIOperation)
+			doc.Paths[path] = item
+		}
+
+		for _, method := range methods {
+			if !containsMethod(openAPIMethods, method) {
+				continue
+			}
+			key := strings.ToLower(method)
+			if _, found := item[key]; found && r.Method == anyMethod {
+				continue
+			}
+			item[key] = openAPIOperation{
+				Summary:    r.Handler,
+				Parameters: parameters,
+				Responses: map[string]openAPIResponse{
+					"default": {Description: "Default response"},
+				},
+			}
+		}
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
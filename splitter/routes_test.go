@@ -0,0 +1,113 @@
+package splitter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type namedHandler string
+
+func (n namedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
+
+func (n namedHandler) String() string {
+	return string(n)
+}
+
+func serveFiles(w http.ResponseWriter, r *http.Request) {}
+
+func newRoutesSplitter(t *testing.T) *Splitter {
+	t.Helper()
+
+	orders := newSplitter(t,
+		WithMethodSplit(http.MethodGet, "orders/{order}", namedHandler("get order")),
+		WithSplit("/", namedHandler("orders root")),
+	)
+
+	return newSplitter(t,
+		WithPrefix("/api"),
+		WithSplit("users/{user}", orders),
+		WithMethodSplit(http.MethodPost, "users", namedHandler("create user")),
+		WithSplit("files/{path...}", http.HandlerFunc(serveFiles)),
+	)
+}
+
+func TestRoutes(t *testing.T) {
+	routes := newRoutesSplitter(t).Routes()
+
+	expected := []Route{
+		{Path: "/api/files/{path...}", Handler: "github.com/mbanzon/middlex/v4/splitter.serveFiles"},
+		{Path: "/api/users", Method: http.MethodPost, Handler: "create user"},
+		{Path: "/api/users/{user}", Handler: "orders root"},
+		{Path: "/api/users/{user}/orders/{order}", Method: http.MethodGet, Handler: "get order"},
+	}
+
+	if len(routes) != len(expected) {
+		t.Fatal("unexpected routes:", routes)
+	}
+
+	for i := range expected {
+		if routes[i] != expected[i] {
+			t.Fatal("unexpected route:", routes[i])
+		}
+	}
+}
+
+func TestFormatRoutes(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(FormatRoutes(newRoutesSplitter(t).Routes())), "\n")
+
+	if len(lines) != 5 {
+		t.Fatal("unexpected number of lines:", len(lines))
+	}
+
+	if strings.Join(strings.Fields(lines[2]), " ") != "POST /api/users create user" {
+		t.Fatal("unexpected line:", lines[2])
+	}
+
+	if strings.Join(strings.Fields(lines[3]), " ") != "* /api/users/{user} orders root" {
+		t.Fatal("unexpected line:", lines[3])
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	data, err := OpenAPI(newRoutesSplitter(t).Routes(), "Test API", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			Parameters []struct {
+				Name     string `json:"name"`
+				In       string `json:"in"`
+				Required bool   `json:"required"`
+			} `json:"parameters"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.OpenAPI != "3.0.3" || len(doc.Paths) != 4 {
+		t.Fatal("unexpected document:", string(data))
+	}
+
+	order := doc.Paths["/api/users/{user}/orders/{order}"]
+	if len(order) != 1 || len(order["get"].Parameters) != 2 {
+		t.Fatal("unexpected path item:", order)
+	}
+
+	if p := order["get"].Parameters[1]; p.Name != "order" || p.In != "path" || !p.Required {
+		t.Fatal("unexpected parameter:", p)
+	}
+
+	if len(doc.Paths["/api/users/{user}"]) != 5 {
+		t.Fatal("unexpected operations for any method:", doc.Paths["/api/users/{user}"])
+	}
+
+	if _, found := doc.Paths["/api/files/{path}"]; !found {
+		t.Fatal("catch-all parameter not rendered as path parameter")
+	}
+}
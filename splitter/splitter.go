@@ -3,26 +3,86 @@ package splitter
 import (
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type Splitter struct {
-	prefix         string
-	splits         map[string]http.Handler
+	prefix string
+
+	cleanPath          bool
+	redirectCode       int
+	rejectEncodedSlash bool
+
+	// routes and defaultHandler hold the configuration given to New, the
+	// splits in use are held by the current table.
+	routes         []*route
 	defaultHandler http.Handler
+
+	notFoundHandler http.Handler
+	fallbackHandler http.Handler
+
+	middleware []Middleware
+	notFound   http.Handler
+
+	table   atomic.Value
+	mutex   *sync.Mutex
+	retired []*table
+	err     error
 }
 
 type ConfigFunc func(*Splitter)
 
+// New creates a new Splitter with the given configuration applied. New
+// panics if the configuration is invalid, use NewChecked to get the error
+// instead.
 func New(config ...ConfigFunc) *Splitter {
+	sh, err := NewChecked(config...)
+	if err != nil {
+		panic(err)
+	}
+	return sh
+}
+
+// NewChecked creates a new Splitter like New, but returns an error instead
+// of panicking if a split pattern or proxy upstream is invalid or if two
+// splits would match exactly the same requests and methods.
+func NewChecked(config ...ConfigFunc) (*Splitter, error) {
 	sh := &Splitter{
-		splits: make(map[string]http.Handler),
+		mutex: &sync.Mutex{},
 	}
 
 	for _, confFn := range config {
 		confFn(sh)
 	}
 
-	return sh
+	if sh.err != nil {
+		return nil, sh.err
+	}
+
+	for _, rt := range sh.routes {
+		segments, err := parsePattern(rt.pattern)
+		if err != nil {
+			return nil, err
+		}
+		rt.segments = segments
+		sh.wrap(rt)
+	}
+	sh.notFound = chain(sh.middleware, http.HandlerFunc(sh.serveNotFound))
+
+	t, err := sh.newTable(sh.routes, sh.defaultHandler)
+	if err != nil {
+		return nil, err
+	}
+	sh.table.Store(t)
+
+	return sh, nil
+}
+
+func (sh *Splitter) setErr(err error) {
+	if sh.err == nil {
+		sh.err = err
+	}
 }
 
 func WithPrefix(prefix string) ConfigFunc {
@@ -31,58 +91,104 @@ func WithPrefix(prefix string) ConfigFunc {
 	}
 }
 
-func WithSplit(path string, h http.Handler) ConfigFunc {
+// WithSplit returns a ConfigFunc that routes requests matching the given
+// path to h. The path can span multiple segments like "api/v2/admin",
+// contain named parameters like "users/{id}" and end with a catch-all
+// parameter like "files/{path...}". The captured values are available to h
+// through Param. When several splits match a request the longest wins.
+func WithSplit(path string, h http.Handler, opts ...SplitOption) ConfigFunc {
+	return WithMethodSplit(anyMethod, path, h, opts...)
+}
+
+// WithMethodSplit returns a ConfigFunc that routes requests matching the
+// given path and method to h. Requests matching the path of method splits
+// but none of their methods are answered with http.StatusMethodNotAllowed,
+// unless a split for any method (WithSplit) has been given for the same
+// path. HEAD requests are routed to the GET split unless a HEAD split is
+// given, and OPTIONS requests are answered automatically.
+func WithMethodSplit(method, path string, h http.Handler, opts ...SplitOption) ConfigFunc {
 	return func(sh *Splitter) {
-		sh.splits[path] = h
+		sh.routes = append(sh.routes, newRoute(method, path, h, opts))
 	}
 }
 
+// WithDefaultHandler returns a ConfigFunc that routes requests within the
+// prefix that match no split to h.
 func WithDefaultHandler(h http.Handler) ConfigFunc {
 	return func(sh *Splitter) {
 		sh.defaultHandler = h
 	}
 }
 
+// ServeHTTP routes the request to the preferred split matching the path
+// after the prefix. The split handler is given a copy of the request with
+// the matched part of the path removed, the request itself is never
+// modified. Requests outside the prefix are passed unchanged and without
+// the middleware to the fallback handler, if given, and otherwise to the
+// not found handler. Paths are only rejected or redirected within the
+// prefix. Requests matching nothing are passed to the default handler, if
+// given, and otherwise to the not found handler.
 func (sh *Splitter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if strings.HasPrefix(r.RequestURI, sh.prefix) {
-		originalURI := r.RequestURI
-		r.RequestURI = r.RequestURI[len(sh.prefix):]
-		r.URL.Path = r.RequestURI
-		path := popURIArg(r)
-		hitHandler := false
-		for p, h := range sh.splits {
-			if path == p {
-				hitHandler = true
-				h.ServeHTTP(w, r)
-				// TODO: should we break here?
-			}
-		}
-		r.RequestURI = originalURI
-		r.URL.Path = originalURI
-		if !hitHandler && sh.defaultHandler != nil {
-			sh.defaultHandler.ServeHTTP(w, r)
+	t := sh.acquire()
+	defer t.release()
+
+	path := r.URL.EscapedPath()
+	canonical := sh.canonicalPath(path)
+
+	within, ok := sh.trimPrefix(canonical)
+	if !ok {
+		if sh.fallbackHandler != nil {
+			sh.fallbackHandler.ServeHTTP(w, r)
+			return
 		}
+		sh.serveNotFound(w, r)
+		return
 	}
-}
 
-func popURIArg(r *http.Request) string {
-	uri := strings.Trim(r.RequestURI, "/")
+	if path != "" && sh.normalize(w, r, path, canonical) {
+		return
+	}
+	if sh.cleanPath && canonical != path {
+		r = withPath(r, canonical)
+	}
+
+	raw := splitPath(within)
+	segments := make([]string, len(raw))
+	for i, s := range raw {
+		segments[i] = unescape(s)
+	}
 
-	if uri == "" {
-		return "/"
+	ep := t.root.lookup(segments)
+	if ep == nil {
+		if t.serveDefault != nil {
+			t.serveDefault.ServeHTTP(w, r)
+			return
+		}
+		sh.notFound.ServeHTTP(w, r)
+		return
 	}
 
-	var arg string
-	index := strings.Index(uri, "/")
-	if index != -1 {
-		arg = uri[:index]
-		r.RequestURI = uri[index:]
-		r.URL.Path = r.RequestURI
-	} else {
-		arg = uri
-		r.RequestURI = "/"
-		r.URL.Path = "/"
+	rt := ep.route(r.Method)
+	if rt == nil {
+		ep.unroutedHandler(r).ServeHTTP(w, r)
+		return
 	}
 
-	return arg
+	_, params, rest := rt.match(segments)
+	rt.serve.ServeHTTP(w, sh.split(r, raw, len(segments)-len(rest), params))
+}
+
+// trimPrefix returns the path after the prefix and whether the path is
+// within the prefix. The prefix only matches whole segments, so the prefix
+// "/api" matches "/api" and "/api/users" but not "/apiary".
+func (sh *Splitter) trimPrefix(path string) (string, bool) {
+	prefix := strings.TrimSuffix(sh.prefix, "/")
+	if !strings.HasPrefix(path, prefix) {
+		return "", false
+	}
+	rest := path[len(prefix):]
+	if rest != "" && rest[0] != '/' {
+		return "", false
+	}
+	return rest, true
 }
@@ -2,9 +2,20 @@ package splitter
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
+func newSplitter(t *testing.T, config ...ConfigFunc) *Splitter {
+	t.Helper()
+
+	s, err := NewChecked(config...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
 func TestCreation(t *testing.T) {
 	count := 0
 
@@ -12,7 +23,7 @@ func TestCreation(t *testing.T) {
 		count++
 	})
 
-	s := New(WithDefaultHandler(defaultHandler))
+	s := newSplitter(t, WithDefaultHandler(defaultHandler))
 	req, _ := http.NewRequest(http.MethodGet, "", nil)
 
 	for i := 0; i < 100; i++ {
@@ -41,7 +52,7 @@ func TestSplit(t *testing.T) {
 		count2++
 	})
 
-	s := New(
+	s := newSplitter(t,
 		WithDefaultHandler(defaultHandler),
 		WithPrefix("/api"),
 		WithSplit("1", handler1),
@@ -51,19 +62,21 @@ func TestSplit(t *testing.T) {
 	req, _ := http.NewRequest(http.MethodGet, "", nil)
 
 	for i := 0; i < 100; i++ {
-		s.ServeHTTP(nil, req)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Fatal("unexpected status:", rec.Code)
+		}
 	}
 
 	if defaultCount != 0 && count1 != 0 && count2 != 0 {
 		t.Fatal()
 	}
 
-	reqDef, _ := http.NewRequest(http.MethodGet, "", nil)
-	reqDef.RequestURI = "/api"
-	req1, _ := http.NewRequest(http.MethodGet, "", nil)
-	req1.RequestURI = "/api/1/bob"
-	req2, _ := http.NewRequest(http.MethodGet, "", nil)
-	req2.RequestURI = "/api/2"
+	reqDef, _ := http.NewRequest(http.MethodGet, "/api", nil)
+	req1, _ := http.NewRequest(http.MethodGet, "/api/1/bob", nil)
+	req2, _ := http.NewRequest(http.MethodGet, "/api/2", nil)
 
 	for i := 0; i < 100; i++ {
 		s.ServeHTTP(nil, reqDef)
@@ -83,3 +96,258 @@ func TestSplit(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestParams(t *testing.T) {
+	var id, rest, path string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = Param(r, "id")
+		rest = Param(r, "rest")
+		path = r.URL.Path
+	})
+
+	s := newSplitter(t,
+		WithPrefix("/api"),
+		WithSplit("users/{id}/orders", handler),
+		WithSplit("files/{rest...}", handler),
+	)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/users/42/orders/7", nil)
+	s.ServeHTTP(nil, req)
+
+	if id != "42" {
+		t.Fatal("unexpected id parameter:", id)
+	}
+
+	if path != "/7" {
+		t.Fatal("unexpected path:", path)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/files/a/b/c.txt", nil)
+	s.ServeHTTP(nil, req)
+
+	if rest != "a/b/c.txt" {
+		t.Fatal("unexpected rest parameter:", rest)
+	}
+
+	if path != "/" {
+		t.Fatal("unexpected path:", path)
+	}
+
+	if Param(req, "rest") != "" {
+		t.Fatal("parameter leaked into the original request")
+	}
+}
+
+func TestRequestNotModified(t *testing.T) {
+	var splitReq *http.Request
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		splitReq = r
+	})
+
+	s := newSplitter(t, WithPrefix("/api"), WithSplit("users/{id}", handler))
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/users/a%2Fb/orders/x%2Fy?q=1", nil)
+	req.RequestURI = req.URL.RequestURI()
+	s.ServeHTTP(nil, req)
+
+	if req.URL.EscapedPath() != "/api/users/a%2Fb/orders/x%2Fy" || req.RequestURI != "/api/users/a%2Fb/orders/x%2Fy?q=1" {
+		t.Fatal("original request modified:", req.RequestURI)
+	}
+
+	if splitReq.URL == req.URL {
+		t.Fatal("split request shares the URL of the original request")
+	}
+
+	if Param(splitReq, "id") != "a/b" {
+		t.Fatal("unexpected id parameter:", Param(splitReq, "id"))
+	}
+
+	if splitReq.URL.Path != "/orders/x/y" || splitReq.URL.EscapedPath() != "/orders/x%2Fy" {
+		t.Fatal("unexpected path:", splitReq.URL.Path, splitReq.URL.EscapedPath())
+	}
+
+	if splitReq.RequestURI != "/orders/x%2Fy?q=1" {
+		t.Fatal("unexpected request URI:", splitReq.RequestURI)
+	}
+
+	if MatchedPath(splitReq) != "/api/users/a/b" {
+		t.Fatal("unexpected matched path:", MatchedPath(splitReq))
+	}
+
+	if RemainingPath(splitReq) != "/orders/x/y" {
+		t.Fatal("unexpected remaining path:", RemainingPath(splitReq))
+	}
+}
+
+func TestStaticBeforeParam(t *testing.T) {
+	hit := ""
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hit = name
+		})
+	}
+
+	s := newSplitter(t,
+		WithSplit("users/{id}", handler("param")),
+		WithSplit("users/me", handler("static")),
+		WithSplit("{rest...}", handler("catch-all")),
+	)
+
+	tests := map[string]string{
+		"/users/me":     "static",
+		"/users/me/x":   "static",
+		"/users/42":     "param",
+		"/users/42/x/y": "param",
+		"/users":        "catch-all",
+		"/other/path":   "catch-all",
+	}
+
+	for uri, expected := range tests {
+		req, _ := http.NewRequest(http.MethodGet, uri, nil)
+		s.ServeHTTP(nil, req)
+
+		if hit != expected {
+			t.Fatal("unexpected handler for", uri, hit)
+		}
+	}
+}
+
+func TestNestedParams(t *testing.T) {
+	var user, order, matched, remaining string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = Param(r, "user")
+		order = Param(r, "order")
+		matched = MatchedPath(r)
+		remaining = RemainingPath(r)
+	})
+
+	orders := newSplitter(t, WithSplit("orders/{order}", handler))
+	s := newSplitter(t, WithSplit("users/{user}", orders))
+
+	req, _ := http.NewRequest(http.MethodGet, "/users/42/orders/7", nil)
+	s.ServeHTTP(nil, req)
+
+	if user != "42" || order != "7" {
+		t.Fatal("unexpected parameters:", user, order)
+	}
+
+	if matched != "/users/42/orders/7" || remaining != "/" {
+		t.Fatal("unexpected paths:", matched, remaining)
+	}
+}
+
+func TestInvalidSplits(t *testing.T) {
+	empty := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := [][]ConfigFunc{
+		{WithSplit("users/{id}", empty), WithSplit("users/{name}", empty)},
+		{WithSplit("users", empty), WithSplit("/users/", empty)},
+		{WithSplit("files/{rest...}/x", empty)},
+		{WithSplit("users/{}", empty)},
+		{WithSplit("users/{id", empty)},
+		{WithSplit("users/x{id}", empty)},
+	}
+
+	for _, config := range tests {
+		if _, err := NewChecked(config...); err == nil {
+			t.Fatal("expected error for invalid splits")
+		}
+	}
+}
+
+func TestMethodSplits(t *testing.T) {
+	hit := ""
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hit = name
+		})
+	}
+
+	s := newSplitter(t,
+		WithMethodSplit(http.MethodGet, "items", handler("get")),
+		WithMethodSplit(http.MethodPost, "items", handler("post")),
+		WithMethodSplit(http.MethodDelete, "items/{id}", handler("delete")),
+		WithSplit("items/{id}", handler("any")),
+	)
+
+	tests := []struct {
+		method, uri, hit string
+	}{
+		{http.MethodGet, "/items", "get"},
+		{http.MethodHead, "/items", "get"},
+		{http.MethodPost, "/items", "post"},
+		{http.MethodDelete, "/items/1", "delete"},
+		{http.MethodPut, "/items/1", "any"},
+		{http.MethodOptions, "/items/1", "any"},
+	}
+
+	for _, test := range tests {
+		hit = ""
+		req, _ := http.NewRequest(test.method, test.uri, nil)
+		s.ServeHTTP(httptest.NewRecorder(), req)
+
+		if hit != test.hit {
+			t.Fatal("unexpected handler for", test.method, test.uri, hit)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("unexpected call to handler")
+	})
+
+	s := newSplitter(t,
+		WithMethodSplit(http.MethodGet, "items", handler),
+		WithMethodSplit(http.MethodPost, "items", handler),
+	)
+
+	req, _ := http.NewRequest(http.MethodPut, "/items", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatal("unexpected status code:", rec.Code)
+	}
+
+	if rec.Header().Get("Allow") != "GET, HEAD, OPTIONS, POST" {
+		t.Fatal("unexpected Allow header:", rec.Header().Get("Allow"))
+	}
+
+	req, _ = http.NewRequest(http.MethodOptions, "/items", nil)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatal("unexpected status code:", rec.Code)
+	}
+
+	if rec.Header().Get("Allow") != "GET, HEAD, OPTIONS, POST" {
+		t.Fatal("unexpected Allow header:", rec.Header().Get("Allow"))
+	}
+}
+
+func TestConflictingMethodSplits(t *testing.T) {
+	empty := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	_, err := NewChecked(
+		WithMethodSplit(http.MethodGet, "items/{id}", empty),
+		WithMethodSplit(http.MethodGet, "items/{name}", empty),
+	)
+	if err == nil {
+		t.Fatal("expected error for conflicting method splits")
+	}
+}
+
+func TestNewPanics(t *testing.T) {
+	if s := New(WithSplit("users/{id}", http.NotFoundHandler())); s == nil {
+		t.Fatal("expected splitter")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic for invalid pattern")
+		}
+	}()
+	New(WithSplit("users/{id", http.NotFoundHandler()))
+}
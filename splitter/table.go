@@ -0,0 +1,215 @@
+package splitter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// table is an immutable routing table. Changing the splits of a Splitter
+// creates a new table that replaces the current one, so requests never
+// need to lock the table.
+type table struct {
+	// active is the number of requests being served using the table.
+	active int64
+
+	routes         []*route
+	root           *node
+	defaultHandler http.Handler
+
+	// serveDefault is the default handler wrapped in the middleware of the
+	// Splitter.
+	serveDefault http.Handler
+}
+
+// newTable creates a table routing to the given routes, which must have
+// been parsed and wrapped.
+func (sh *Splitter) newTable(routes []*route, defaultHandler http.Handler) (*table, error) {
+	t := &table{
+		routes:         routes,
+		root:           newNode(),
+		defaultHandler: defaultHandler,
+	}
+	if defaultHandler != nil {
+		t.serveDefault = chain(sh.middleware, defaultHandler)
+	}
+
+	names := make(map[string]bool)
+	for _, rt := range routes {
+		if rt.name != "" {
+			if names[rt.name] {
+				return nil, fmt.Errorf("splitter: split name %q given more than once", rt.name)
+			}
+			names[rt.name] = true
+		}
+
+		if err := t.root.insert(rt); err != nil {
+			return nil, err
+		}
+	}
+
+	t.root.walk(func(ep *endpoint) {
+		ep.unrouted = make(map[string]http.Handler, len(ep.routes))
+		for method, rt := range ep.routes {
+			ep.unrouted[method] = chain(sh.middleware, chain(rt.middleware, http.HandlerFunc(ep.serveUnrouted)))
+		}
+	})
+
+	return t, nil
+}
+
+// acquire returns the current table and marks it as used by a request
+// until release is called. A table is never marked as used after it has
+// been replaced, which is what WaitRetired relies on.
+func (sh *Splitter) acquire() *table {
+	for {
+		t := sh.table.Load().(*table)
+		atomic.AddInt64(&t.active, 1)
+		if sh.table.Load().(*table) == t {
+			return t
+		}
+		atomic.AddInt64(&t.active, -1)
+	}
+}
+
+func (t *table) release() {
+	atomic.AddInt64(&t.active, -1)
+}
+
+// update replaces the current table with the one returned by fn.
+func (sh *Splitter) update(fn func(current *table) (*table, error)) error {
+	sh.mutex.Lock()
+	defer sh.mutex.Unlock()
+
+	current := sh.table.Load().(*table)
+	t, err := fn(current)
+	if err != nil {
+		return err
+	}
+
+	sh.table.Store(t)
+	sh.pruneRetired()
+	if atomic.LoadInt64(&current.active) > 0 {
+		sh.retired = append(sh.retired, current)
+	}
+	return nil
+}
+
+// pruneRetired drops the retired tables without requests in flight. A
+// retired table never gets new requests, so it is done for good. The mutex
+// must be held.
+func (sh *Splitter) pruneRetired() {
+	busy := sh.retired[:0]
+	for _, t := range sh.retired {
+		if atomic.LoadInt64(&t.active) > 0 {
+			busy = append(busy, t)
+		}
+	}
+	for i := len(busy); i < len(sh.retired); i++ {
+		sh.retired[i] = nil
+	}
+	sh.retired = busy
+}
+
+// AddSplit adds a split for any method while the Splitter is serving
+// requests. The path is given as for WithSplit. An error is returned if the
+// path is invalid or if a split for the same path already exists.
+func (sh *Splitter) AddSplit(path string, h http.Handler, opts ...SplitOption) error {
+	return sh.AddMethodSplit(anyMethod, path, h, opts...)
+}
+
+// AddMethodSplit adds a split for the given method while the Splitter is
+// serving requests, see WithMethodSplit.
+func (sh *Splitter) AddMethodSplit(method, path string, h http.Handler, opts ...SplitOption) error {
+	rt := newRoute(method, path, h, opts)
+	segments, err := parsePattern(path)
+	if err != nil {
+		return err
+	}
+	rt.segments = segments
+	sh.wrap(rt)
+
+	return sh.update(func(current *table) (*table, error) {
+		routes := make([]*route, 0, len(current.routes)+1)
+		routes = append(routes, current.routes...)
+		routes = append(routes, rt)
+		return sh.newTable(routes, current.defaultHandler)
+	})
+}
+
+// RemoveSplit removes the split for any method for the given path. An
+// error is returned if there is no such split.
+func (sh *Splitter) RemoveSplit(path string) error {
+	return sh.RemoveMethodSplit(anyMethod, path)
+}
+
+// RemoveMethodSplit removes the split for the given method and path. An
+// error is returned if there is no such split.
+func (sh *Splitter) RemoveMethodSplit(method, path string) error {
+	segments, err := parsePattern(path)
+	if err != nil {
+		return err
+	}
+
+	return sh.update(func(current *table) (*table, error) {
+		routes := make([]*route, 0, len(current.routes))
+		for _, rt := range current.routes {
+			if rt.method != method || !sameSegments(rt.segments, segments) {
+				routes = append(routes, rt)
+			}
+		}
+		if len(routes) == len(current.routes) {
+			return nil, fmt.Errorf("splitter: no split %q", path)
+		}
+		return sh.newTable(routes, current.defaultHandler)
+	})
+}
+
+// ReplaceDefault replaces the default handler while the Splitter is
+// serving requests.
+func (sh *Splitter) ReplaceDefault(h http.Handler) {
+	_ = sh.update(func(current *table) (*table, error) {
+		return sh.newTable(current.routes, h)
+	})
+}
+
+// WaitRetired waits until the requests that were being served when the
+// splits were last changed have finished, or until the context is done.
+// Requests that have started before a change finish using the splits as
+// they were when the request started.
+func (sh *Splitter) WaitRetired(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		sh.mutex.Lock()
+		sh.pruneRetired()
+		busy := len(sh.retired)
+		sh.mutex.Unlock()
+
+		if busy == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// sameSegments reports whether two patterns match exactly the same paths.
+func sameSegments(a, b []segment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].kind != b[i].kind || (a[i].kind == staticSegment && a[i].value != b[i].value) {
+			return false
+		}
+	}
+	return true
+}
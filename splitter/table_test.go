@@ -0,0 +1,175 @@
+package splitter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRuntimeSplits(t *testing.T) {
+	hit := ""
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hit = name
+		})
+	}
+
+	s := newSplitter(t, WithSplit("a", handler("a")), WithDefaultHandler(handler("default")))
+
+	serve := func(method, path string) string {
+		hit = ""
+		req, _ := http.NewRequest(method, path, nil)
+		s.ServeHTTP(httptest.NewRecorder(), req)
+		return hit
+	}
+
+	if err := s.AddSplit("b/{id}", handler("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddMethodSplit(http.MethodPost, "b/{id}", handler("post-b")); err != nil {
+		t.Fatal(err)
+	}
+
+	if serve(http.MethodGet, "/b/1") != "b" || serve(http.MethodPost, "/b/1") != "post-b" {
+		t.Fatal("added splits not routed to")
+	}
+
+	if err := s.AddSplit("b/{name}", handler("b")); err == nil {
+		t.Fatal("expected error for conflicting split")
+	}
+
+	if err := s.RemoveSplit("/b/{x}/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if serve(http.MethodGet, "/b/1") != "" || serve(http.MethodPost, "/b/1") != "post-b" {
+		t.Fatal("removed split still routed to")
+	}
+
+	if err := s.RemoveSplit("b/{id}"); err == nil {
+		t.Fatal("expected error for removing unknown split")
+	}
+
+	s.ReplaceDefault(handler("new default"))
+
+	if serve(http.MethodGet, "/c") != "new default" || serve(http.MethodGet, "/a") != "a" {
+		t.Fatal("default handler not replaced")
+	}
+}
+
+func TestWaitRetired(t *testing.T) {
+	entered := make(chan bool)
+	release := make(chan bool)
+	blocking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- true
+		<-release
+	})
+
+	s := newSplitter(t, WithSplit("a", blocking))
+
+	done := make(chan bool)
+	go func() {
+		req, _ := http.NewRequest(http.MethodGet, "/a", nil)
+		s.ServeHTTP(httptest.NewRecorder(), req)
+		done <- true
+	}()
+	<-entered
+
+	if err := s.RemoveSplit("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.WaitRetired(ctx); err != context.DeadlineExceeded {
+		t.Fatal("unexpected error while request is in flight:", err)
+	}
+
+	close(release)
+	<-done
+
+	if err := s.WaitRetired(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetiredPruned(t *testing.T) {
+	entered := make(chan bool)
+	release := make(chan bool)
+	blocking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- true
+		<-release
+	})
+	empty := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	s := newSplitter(t, WithSplit("a", blocking))
+
+	for i := 0; i < 100; i++ {
+		if err := s.AddSplit(fmt.Sprintf("s%d", i), empty); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.retired) != 0 {
+		t.Fatal("unexpected retired tables without requests:", len(s.retired))
+	}
+
+	done := make(chan bool)
+	go func() {
+		req, _ := http.NewRequest(http.MethodGet, "/a", nil)
+		s.ServeHTTP(httptest.NewRecorder(), req)
+		done <- true
+	}()
+	<-entered
+
+	s.ReplaceDefault(empty)
+	s.ReplaceDefault(empty)
+	if len(s.retired) != 1 {
+		t.Fatal("unexpected retired tables with a request in flight:", len(s.retired))
+	}
+
+	close(release)
+	<-done
+
+	s.ReplaceDefault(empty)
+	if len(s.retired) != 0 {
+		t.Fatal("unexpected retired tables after the request:", len(s.retired))
+	}
+}
+
+func TestConcurrentRuntimeSplits(t *testing.T) {
+	empty := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	s := newSplitter(t, WithSplit("a", empty))
+
+	wg := sync.WaitGroup{}
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/s%d", i%10), nil)
+				s.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		path := fmt.Sprintf("s%d", i%10)
+		if i%20 < 10 {
+			if err := s.AddSplit(path, empty); err != nil {
+				t.Fatal(err)
+			}
+		} else if err := s.RemoveSplit(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wg.Wait()
+
+	if err := s.WaitRetired(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
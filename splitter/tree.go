@@ -0,0 +1,123 @@
+package splitter
+
+import (
+	"fmt"
+)
+
+// node is a node in the routing tree. Each node represents a segment of a
+// split pattern and holds the endpoint of the pattern ending at it.
+type node struct {
+	static   map[string]*node
+	param    *node
+	catchAll *node
+	endpoint *endpoint
+}
+
+func newNode() *node {
+	return &node{
+		static: make(map[string]*node),
+	}
+}
+
+// insert adds the route to the endpoint of the node matching its pattern,
+// creating the nodes and the endpoint as needed.
+func (n *node) insert(rt *route) error {
+	current := n
+	for _, s := range rt.segments {
+		var next **node
+		switch s.kind {
+		case staticSegment:
+			child := current.static[s.value]
+			if child == nil {
+				child = newNode()
+				current.static[s.value] = child
+			}
+			current = child
+			continue
+		case paramSegment:
+			next = &current.param
+		case catchAllSegment:
+			next = &current.catchAll
+		}
+		if *next == nil {
+			*next = newNode()
+		}
+		current = *next
+	}
+
+	if current.endpoint == nil {
+		current.endpoint = newEndpoint(rt)
+		return nil
+	}
+
+	if other, ok := current.endpoint.routes[rt.method]; ok {
+		return fmt.Errorf("splitter: split %q conflicts with split %q", rt.pattern, other.pattern)
+	}
+	current.endpoint.routes[rt.method] = rt
+	return nil
+}
+
+// lookup finds the endpoint for the path segments. The pattern matching
+// the most segments with static segments and parameters wins, so a
+// parameter route matching the whole path is preferred over a static route
+// matching only a prefix of it. When patterns match the same number of
+// segments static segments are preferred over parameters which are
+// preferred over catch-all parameters. The empty pattern only matches the
+// empty path.
+func (n *node) lookup(segments []string) *endpoint {
+	if len(segments) == 0 {
+		if n.catchAll != nil {
+			return n.catchAll.endpoint
+		}
+		return n.endpoint
+	}
+	ep, _ := n.lookupFrom(segments, 0)
+	return ep
+}
+
+// lookupFrom returns the preferred endpoint below the node together with
+// the number of segments its pattern matched before any catch-all.
+func (n *node) lookupFrom(segments []string, depth int) (*endpoint, int) {
+	var best *endpoint
+	bestDepth := -1
+
+	if depth < len(segments) {
+		if child := n.static[segments[depth]]; child != nil {
+			best, bestDepth = child.lookupFrom(segments, depth+1)
+		}
+		if n.param != nil {
+			if ep, d := n.param.lookupFrom(segments, depth+1); ep != nil && d > bestDepth {
+				best, bestDepth = ep, d
+			}
+		}
+	}
+
+	if best != nil && bestDepth > depth {
+		return best, bestDepth
+	}
+
+	if n.catchAll != nil {
+		return n.catchAll.endpoint, depth
+	}
+
+	if depth == 0 || n.endpoint == nil {
+		return nil, -1
+	}
+	return n.endpoint, depth
+}
+
+// walk calls fn for the endpoints of the node and its descendants.
+func (n *node) walk(fn func(*endpoint)) {
+	if n.endpoint != nil {
+		fn(n.endpoint)
+	}
+	for _, child := range n.static {
+		child.walk(fn)
+	}
+	if n.param != nil {
+		n.param.walk(fn)
+	}
+	if n.catchAll != nil {
+		n.catchAll.walk(fn)
+	}
+}
@@ -0,0 +1,203 @@
+package splitter
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// linearMatch is the matching done before the routing tree was
+// introduced. Every route is tried and the preferred match is kept. It is
+// kept here as a reference for the tree and for the benchmarks.
+func linearMatch(routes []*route, segments []string) *route {
+	var best *route
+	for _, rt := range routes {
+		if ok, _, _ := rt.match(segments); ok && (best == nil || preferred(rt, best)) {
+			best = rt
+		}
+	}
+	return best
+}
+
+// preferred reports whether rt should be chosen over other when both
+// match a request.
+func preferred(rt, other *route) bool {
+	if fixed, otherFixed := fixedSegments(rt), fixedSegments(other); fixed != otherFixed {
+		return fixed > otherFixed
+	}
+	for i := 0; i < len(rt.segments) && i < len(other.segments); i++ {
+		if rt.segments[i].kind != other.segments[i].kind {
+			return rt.segments[i].kind < other.segments[i].kind
+		}
+	}
+	return len(rt.segments) > len(other.segments)
+}
+
+// fixedSegments returns the number of segments of the route before any
+// catch-all parameter.
+func fixedSegments(rt *route) int {
+	for i, s := range rt.segments {
+		if s.kind == catchAllSegment {
+			return i
+		}
+	}
+	return len(rt.segments)
+}
+
+func TestLongestMatch(t *testing.T) {
+	hit := ""
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hit != "" {
+				t.Fatal("more than one handler called")
+			}
+			hit = name
+		})
+	}
+
+	s := newSplitter(t,
+		WithSplit("api", handler("api")),
+		WithSplit("api/v2", handler("v2")),
+		WithSplit("api/v2/admin", handler("admin")),
+		WithSplit("api/{version}/users", handler("users")),
+	)
+
+	tests := map[string]string{
+		"/api":              "api",
+		"/api/v1":           "api",
+		"/api/v2":           "v2",
+		"/api/v2/other":     "v2",
+		"/api/v2/admin":     "admin",
+		"/api/v2/admin/x/y": "admin",
+		"/api/v1/users":     "users",
+		"/api/v2/users":     "users",
+		"/api/v2/users/42":  "users",
+	}
+
+	for path, expected := range tests {
+		hit = ""
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		s.ServeHTTP(httptest.NewRecorder(), req)
+
+		if hit != expected {
+			t.Fatal("unexpected handler for", path, hit)
+		}
+	}
+}
+
+func TestTreeMatchesLinear(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	words := []string{"a", "b", "c", "{p}", "{q...}"}
+	root := newNode()
+	var routes []*route
+	for i := 0; i < 200; i++ {
+		pattern := randomPattern(rnd, words)
+		segments, err := parsePattern(pattern)
+		if err != nil {
+			continue
+		}
+		rt := &route{pattern: pattern, segments: segments}
+		if root.insert(rt) == nil {
+			routes = append(routes, rt)
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		segments := splitPath(randomPattern(rnd, []string{"a", "b", "c", "d"}))
+
+		expected := linearMatch(routes, segments)
+		ep := root.lookup(segments)
+
+		if expected == nil && ep == nil {
+			continue
+		}
+
+		if expected == nil || ep == nil || ep.first != expected {
+			t.Fatal("tree and linear match differ for", segments)
+		}
+	}
+}
+
+func randomPattern(rnd *rand.Rand, words []string) string {
+	pattern := ""
+	for n := rnd.Intn(5); n > 0; n-- {
+		pattern += "/" + words[rnd.Intn(len(words))]
+	}
+	return pattern
+}
+
+// baselineMatch is the matching of the original splitter, which split on
+// the first segment only and compared it to every split in a map. It is
+// kept here as a baseline for the benchmarks.
+func baselineMatch(splits map[string]http.Handler, uri string) http.Handler {
+	uri = strings.Trim(uri, "/")
+	if index := strings.Index(uri, "/"); index != -1 {
+		uri = uri[:index]
+	}
+
+	var hit http.Handler
+	for p, h := range splits {
+		if uri == p {
+			hit = h
+		}
+	}
+	return hit
+}
+
+func benchmarkRoutes(n int) ([]*route, *node, [][]string) {
+	root := newNode()
+	var routes []*route
+	var paths [][]string
+	for i := 0; i < n; i++ {
+		pattern := fmt.Sprintf("service%d/{id}/items%d", i, i%10)
+		segments, _ := parsePattern(pattern)
+		rt := &route{pattern: pattern, segments: segments}
+		_ = root.insert(rt)
+		routes = append(routes, rt)
+		paths = append(paths, splitPath(fmt.Sprintf("/service%d/42/items%d/x", i, i%10)))
+	}
+	return routes, root, paths
+}
+
+func benchmarkBaseline(b *testing.B, n int) {
+	splits := make(map[string]http.Handler)
+	var uris []string
+	for i := 0; i < n; i++ {
+		splits[fmt.Sprintf("service%d", i)] = http.NotFoundHandler()
+		uris = append(uris, fmt.Sprintf("/service%d/42/items%d/x", i, i%10))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		baselineMatch(splits, uris[i%len(uris)])
+	}
+}
+
+func benchmarkLinear(b *testing.B, n int) {
+	routes, _, paths := benchmarkRoutes(n)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearMatch(routes, paths[i%len(paths)])
+	}
+}
+
+func benchmarkTree(b *testing.B, n int) {
+	_, root, paths := benchmarkRoutes(n)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		root.lookup(paths[i%len(paths)])
+	}
+}
+
+func BenchmarkBaseline10(b *testing.B)  { benchmarkBaseline(b, 10) }
+func BenchmarkBaseline100(b *testing.B) { benchmarkBaseline(b, 100) }
+func BenchmarkBaseline500(b *testing.B) { benchmarkBaseline(b, 500) }
+func BenchmarkLinear10(b *testing.B)    { benchmarkLinear(b, 10) }
+func BenchmarkLinear100(b *testing.B)   { benchmarkLinear(b, 100) }
+func BenchmarkLinear500(b *testing.B)   { benchmarkLinear(b, 500) }
+func BenchmarkTree10(b *testing.B)      { benchmarkTree(b, 10) }
+func BenchmarkTree100(b *testing.B)     { benchmarkTree(b, 100) }
+func BenchmarkTree500(b *testing.B)     { benchmarkTree(b, 500) }
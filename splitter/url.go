@@ -0,0 +1,94 @@
+package splitter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URL builds the path for the named split, including the prefix and the
+// paths of any parent splitters. Splits of nested splitters are found as
+// well. The parameters of the path are given as name and value pairs, like
+// URL("order", "user", "42", "order", "7"), and are escaped as needed. An
+// error is returned if there is no split with the name or if a parameter
+// is missing.
+func (sh *Splitter) URL(name string, params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("splitter: parameter %q has no value", params[len(params)-1])
+	}
+
+	values := make(map[string]string)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	path, found, err := sh.buildURL(name, values)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("splitter: no split named %q", name)
+	}
+	if path == "" {
+		path = "/"
+	}
+	return path, nil
+}
+
+func (sh *Splitter) buildURL(name string, values map[string]string) (string, bool, error) {
+	t := sh.table.Load().(*table)
+	base := strings.TrimSuffix(sh.prefix, "/")
+
+	for _, rt := range t.routes {
+		if rt.name == name {
+			path, err := buildPath(rt, values)
+			return base + path, true, err
+		}
+	}
+
+	for _, rt := range t.routes {
+		nested, ok := rt.handler.(*Splitter)
+		if !ok {
+			continue
+		}
+
+		sub, found, err := nested.buildURL(name, values)
+		if !found || err != nil {
+			if found {
+				return "", true, err
+			}
+			continue
+		}
+
+		path, err := buildPath(rt, values)
+		return base + path + sub, true, err
+	}
+
+	return "", false, nil
+}
+
+// buildPath builds the escaped path of the route from the parameter values.
+func buildPath(rt *route, values map[string]string) (string, error) {
+	var b strings.Builder
+	for _, s := range rt.segments {
+		if s.kind == staticSegment {
+			b.WriteString("/" + url.PathEscape(s.value))
+			continue
+		}
+
+		value, found := values[s.value]
+		if !found {
+			return "", fmt.Errorf("splitter: missing parameter %q for split %q", s.value, rt.pattern)
+		}
+
+		if s.kind == paramSegment {
+			b.WriteString("/" + url.PathEscape(value))
+			continue
+		}
+
+		for _, part := range splitPath(value) {
+			b.WriteString("/" + url.PathEscape(part))
+		}
+	}
+	return b.String(), nil
+}
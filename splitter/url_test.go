@@ -0,0 +1,105 @@
+package splitter
+
+import (
+	"net/http"
+	"testing"
+)
+
+func newURLSplitter(t *testing.T) *Splitter {
+	t.Helper()
+
+	orders := newSplitter(t,
+		WithPrefix("/v2/"),
+		WithMethodSplit(http.MethodGet, "orders/{order}", namedHandler("get order"), Named("order")),
+		WithSplit("/", namedHandler("orders root"), Named("orders")),
+	)
+
+	return newSplitter(t,
+		WithPrefix("/api"),
+		WithSplit("users/{user}", orders),
+		WithSplit("users", namedHandler("users"), Named("users")),
+		WithSplit("files/{path...}", namedHandler("files"), Named("files")),
+	)
+}
+
+func TestURL(t *testing.T) {
+	sh := newURLSplitter(t)
+
+	tests := []struct {
+		name     string
+		params   []string
+		expected string
+	}{
+		{"users", nil, "/api/users"},
+		{"order", []string{"user", "42", "order", "7"}, "/api/users/42/v2/orders/7"},
+		{"orders", []string{"user", "42"}, "/api/users/42/v2"},
+		{"order", []string{"user", "a/b c", "order", "?"}, "/api/users/a%2Fb%20c/v2/orders/%3F"},
+		{"files", []string{"path", "css/main site.css"}, "/api/files/css/main%20site.css"},
+	}
+
+	for _, test := range tests {
+		url, err := sh.URL(test.name, test.params...)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if url != test.expected {
+			t.Fatal("unexpected url:", url)
+		}
+	}
+}
+
+func TestURLRoot(t *testing.T) {
+	sh := newSplitter(t, WithSplit("/", namedHandler("root"), Named("root")))
+
+	url, err := sh.URL("root")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if url != "/" {
+		t.Fatal("unexpected url:", url)
+	}
+}
+
+func TestURLErrors(t *testing.T) {
+	sh := newURLSplitter(t)
+
+	if _, err := sh.URL("unknown"); err == nil {
+		t.Fatal("expected error for unknown name")
+	}
+	if _, err := sh.URL("order", "user", "42"); err == nil {
+		t.Fatal("expected error for missing parameter")
+	}
+	if _, err := sh.URL("order", "user"); err == nil {
+		t.Fatal("expected error for parameter without value")
+	}
+}
+
+func TestURLRuntimeSplit(t *testing.T) {
+	sh := newURLSplitter(t)
+
+	if err := sh.AddSplit("health", namedHandler("health"), Named("health")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	url, err := sh.URL("health")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if url != "/api/health" {
+		t.Fatal("unexpected url:", url)
+	}
+
+	if err := sh.AddSplit("other", namedHandler("other"), Named("health")); err == nil {
+		t.Fatal("expected error for duplicate name")
+	}
+}
+
+func TestDuplicateNames(t *testing.T) {
+	_, err := NewChecked(
+		WithSplit("a", namedHandler("a"), Named("same")),
+		WithSplit("b", namedHandler("b"), Named("same")),
+	)
+	if err == nil {
+		t.Fatal("expected error for duplicate name")
+	}
+}
@@ -0,0 +1,233 @@
+package splitter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VersionSplitter routes requests by the API version they ask for, either
+// through a version header or through the media types of the Accept
+// header like "application/vnd.acme.v2+json" or
+// "application/vnd.acme+json; version=2".
+type VersionSplitter struct {
+	mediaType      string
+	suffix         string
+	header         string
+	versions       map[string]*version
+	defaultVersion string
+	err            error
+}
+
+type version struct {
+	name    string
+	handler http.Handler
+}
+
+// VersionConfigFunc is the type of function used to configure the
+// VersionSplitter.
+type VersionConfigFunc func(*VersionSplitter)
+
+// NewVersion creates a new VersionSplitter with the given configuration
+// applied. An error is returned if no versions are given, if a version is
+// given more than once or if the default version is not given.
+func NewVersion(config ...VersionConfigFunc) (*VersionSplitter, error) {
+	vs := &VersionSplitter{
+		versions: make(map[string]*version),
+	}
+
+	for _, confFn := range config {
+		confFn(vs)
+	}
+
+	if vs.err != nil {
+		return nil, vs.err
+	}
+
+	if len(vs.versions) == 0 {
+		return nil, errors.New("splitter: no versions given")
+	}
+
+	if vs.defaultVersion != "" {
+		if _, found := vs.versions[normalizeVersion(vs.defaultVersion)]; !found {
+			return nil, fmt.Errorf("splitter: default version %q not given", vs.defaultVersion)
+		}
+	}
+
+	return vs, nil
+}
+
+// WithVersion returns a VersionConfigFunc that routes requests asking for
+// the given version to h. Versions are compared without a leading "v", so
+// "v2" and "2" are the same version.
+func WithVersion(name string, h http.Handler) VersionConfigFunc {
+	return func(vs *VersionSplitter) {
+		key := normalizeVersion(name)
+		if _, found := vs.versions[key]; found {
+			if vs.err == nil {
+				vs.err = fmt.Errorf("splitter: version %q given more than once", name)
+			}
+			return
+		}
+		vs.versions[key] = &version{name: name, handler: h}
+	}
+}
+
+// WithDefaultVersion returns a VersionConfigFunc that routes requests not
+// asking for any of the versions to the given version. Without a default
+// version such requests are answered with http.StatusNotAcceptable.
+func WithDefaultVersion(name string) VersionConfigFunc {
+	return func(vs *VersionSplitter) {
+		vs.defaultVersion = name
+	}
+}
+
+// WithVersionHeader returns a VersionConfigFunc that makes the
+// VersionSplitter read the version from the given header (like
+// "Api-Version"). The header takes precedence over the Accept header.
+func WithVersionHeader(header string) VersionConfigFunc {
+	return func(vs *VersionSplitter) {
+		vs.header = header
+	}
+}
+
+// WithMediaType returns a VersionConfigFunc that makes the VersionSplitter
+// read the version from the Accept header, using the given vendor media
+// type and structured syntax suffix. With "application/vnd.acme" and
+// "json" both "application/vnd.acme.v2+json" and
+// "application/vnd.acme+json; version=2" ask for version 2. The responses
+// get a Content-Type of the same form, which the handlers may override.
+func WithMediaType(mediaType, suffix string) VersionConfigFunc {
+	return func(vs *VersionSplitter) {
+		vs.mediaType = strings.ToLower(mediaType)
+		vs.suffix = strings.ToLower(suffix)
+	}
+}
+
+// ServeHTTP routes the request to the handler of the version it asks for.
+func (vs *VersionSplitter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if vs.mediaType != "" {
+		w.Header().Add("Vary", "Accept")
+	}
+	if vs.header != "" {
+		w.Header().Add("Vary", vs.header)
+	}
+
+	v, ok := vs.negotiate(r)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
+
+	if vs.mediaType != "" {
+		contentType := fmt.Sprintf("%s.v%s", vs.mediaType, normalizeVersion(v.name))
+		if vs.suffix != "" {
+			contentType += "+" + vs.suffix
+		}
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	v.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionKey, v.name)))
+}
+
+// negotiate finds the version asked for by the request.
+func (vs *VersionSplitter) negotiate(r *http.Request) (*version, bool) {
+	if vs.header != "" {
+		if name := r.Header.Get(vs.header); name != "" {
+			v, found := vs.versions[normalizeVersion(name)]
+			return v, found
+		}
+	}
+
+	if vs.mediaType != "" {
+		for _, name := range vs.acceptedVersions(r) {
+			if v, found := vs.versions[normalizeVersion(name)]; found {
+				return v, true
+			}
+		}
+	}
+
+	if vs.defaultVersion != "" {
+		return vs.versions[normalizeVersion(vs.defaultVersion)], true
+	}
+
+	return nil, false
+}
+
+// acceptedVersions returns the versions found in the Accept header ordered
+// by their quality values.
+func (vs *VersionSplitter) acceptedVersions(r *http.Request) []string {
+	type accepted struct {
+		version string
+		q       float64
+	}
+
+	var versions []accepted
+	for _, value := range r.Header.Values("Accept") {
+		for _, entry := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(entry))
+			if err != nil {
+				continue
+			}
+
+			q := 1.0
+			if qValue, found := params["q"]; found {
+				if q, err = strconv.ParseFloat(qValue, 64); err != nil {
+					continue
+				}
+			}
+			if q <= 0 {
+				continue
+			}
+
+			if name := vs.mediaTypeVersion(mediaType, params); name != "" {
+				versions = append(versions, accepted{version: name, q: q})
+			}
+		}
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].q > versions[j].q
+	})
+
+	names := make([]string, len(versions))
+	for i, a := range versions {
+		names[i] = a.version
+	}
+	return names
+}
+
+// mediaTypeVersion returns the version asked for by a media type, or an
+// empty string if it does not ask for a version.
+func (vs *VersionSplitter) mediaTypeVersion(mediaType string, params map[string]string) string {
+	if mediaType == vs.mediaType || (vs.suffix != "" && mediaType == vs.mediaType+"+"+vs.suffix) {
+		return params["version"]
+	}
+
+	if strings.HasPrefix(mediaType, vs.mediaType+".") {
+		name := strings.TrimPrefix(mediaType, vs.mediaType+".")
+		if vs.suffix != "" {
+			name = strings.TrimSuffix(name, "+"+vs.suffix)
+		}
+		return name
+	}
+
+	return ""
+}
+
+// Version returns the name of the version a VersionSplitter routed the
+// request to, or an empty string if the request was not routed by a
+// VersionSplitter.
+func Version(r *http.Request) string {
+	name, _ := r.Context().Value(versionKey).(string)
+	return name
+}
+
+func normalizeVersion(name string) string {
+	return strings.TrimPrefix(strings.ToLower(name), "v")
+}
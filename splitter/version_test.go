@@ -0,0 +1,101 @@
+package splitter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newVersionSplitter(t *testing.T, hit *string) *VersionSplitter {
+	t.Helper()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hit = Version(r)
+	})
+
+	vs, err := NewVersion(
+		WithMediaType("application/vnd.acme", "json"),
+		WithVersionHeader("Api-Version"),
+		WithVersion("v1", handler),
+		WithVersion("v2", handler),
+		WithVersion("v3", handler),
+		WithDefaultVersion("v1"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vs
+}
+
+func TestVersionNegotiation(t *testing.T) {
+	hit := ""
+	vs := newVersionSplitter(t, &hit)
+
+	tests := []struct {
+		accept, header, hit string
+	}{
+		{"", "", "v1"},
+		{"application/json", "", "v1"},
+		{"application/vnd.acme.v2+json", "", "v2"},
+		{"application/vnd.acme+json; version=3", "", "v3"},
+		{"application/vnd.acme.v2+json;q=0.5, application/vnd.acme.v3+json", "", "v3"},
+		{"application/vnd.acme.v9+json, application/vnd.acme.v2+json;q=0.1", "", "v2"},
+		{"application/vnd.acme.v3+json;q=0, application/vnd.acme.v2+json;q=0.1", "", "v2"},
+		{"application/vnd.acme.v3+json", "2", "v2"},
+	}
+
+	for _, test := range tests {
+		hit = ""
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		if test.header != "" {
+			req.Header.Set("Api-Version", test.header)
+		}
+		rec := httptest.NewRecorder()
+		vs.ServeHTTP(rec, req)
+
+		if hit != test.hit {
+			t.Fatal("unexpected version for", test.accept, test.header, hit)
+		}
+
+		if rec.Header().Get("Content-Type") != "application/vnd.acme."+test.hit+"+json" {
+			t.Fatal("unexpected Content-Type:", rec.Header().Get("Content-Type"))
+		}
+
+		if vary := rec.Header().Values("Vary"); len(vary) != 2 || vary[0] != "Accept" || vary[1] != "Api-Version" {
+			t.Fatal("unexpected Vary:", vary)
+		}
+	}
+}
+
+func TestVersionNotAcceptable(t *testing.T) {
+	hit := ""
+	vs := newVersionSplitter(t, &hit)
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Api-Version", "v9")
+	rec := httptest.NewRecorder()
+	vs.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotAcceptable || hit != "" {
+		t.Fatal("unexpected response:", rec.Code, hit)
+	}
+}
+
+func TestInvalidVersions(t *testing.T) {
+	empty := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := [][]VersionConfigFunc{
+		{},
+		{WithVersion("v1", empty), WithVersion("1", empty)},
+		{WithVersion("v1", empty), WithDefaultVersion("v2")},
+	}
+
+	for _, config := range tests {
+		if _, err := NewVersion(config...); err == nil {
+			t.Fatal("expected error for invalid versions")
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package splitter
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Weighted distributes requests between several handlers by weight, for
+// example to send a small part of the traffic of a split to a canary:
+//
+//	canary, err := NewWeighted(
+//		WithTarget("stable", 95, stable),
+//		WithTarget("canary", 5, canary),
+//		WithStickyCookie("session"),
+//	)
+//	s := New(WithSplit("api", canary))
+//
+// The weights can be changed while serving requests.
+type Weighted struct {
+	mutex     *sync.Mutex
+	targets   []*target
+	stickyKey StickyFunc
+	err       error
+}
+
+type target struct {
+	name    string
+	weight  int
+	handler http.Handler
+	hits    int64
+}
+
+// StickyFunc is the signature of the functions used to find the key that
+// makes requests stick to the same target. Requests for which an empty
+// string is returned are distributed randomly.
+type StickyFunc func(*http.Request) string
+
+// WeightedConfigFunc is the type of function used to configure the
+// Weighted.
+type WeightedConfigFunc func(*Weighted)
+
+// NewWeighted creates a new Weighted with the given configuration applied.
+// An error is returned if no targets are given, if a target is given more
+// than once or if a weight is negative.
+func NewWeighted(config ...WeightedConfigFunc) (*Weighted, error) {
+	wt := &Weighted{
+		mutex: &sync.Mutex{},
+	}
+
+	for _, confFn := range config {
+		confFn(wt)
+	}
+
+	if wt.err != nil {
+		return nil, wt.err
+	}
+
+	if len(wt.targets) == 0 {
+		return nil, errors.New("splitter: no targets given")
+	}
+
+	return wt, nil
+}
+
+// WithTarget returns a WeightedConfigFunc that adds a target receiving a
+// share of the requests given by its weight relative to the sum of all
+// weights. A target with weight zero receives no requests.
+func WithTarget(name string, weight int, h http.Handler) WeightedConfigFunc {
+	return func(wt *Weighted) {
+		if weight < 0 {
+			wt.setErr(fmt.Errorf("splitter: negative weight %d for target %q", weight, name))
+			return
+		}
+		if wt.target(name) != nil {
+			wt.setErr(fmt.Errorf("splitter: target %q given more than once", name))
+			return
+		}
+		wt.targets = append(wt.targets, &target{name: name, weight: weight, handler: h})
+	}
+}
+
+// WithStickyFunc returns a WeightedConfigFunc that makes requests with the
+// same key stick to the same target. Keys are assigned to targets by
+// consistent hashing, so changing the weights only moves the keys needed
+// to honour the new weights.
+func WithStickyFunc(fn StickyFunc) WeightedConfigFunc {
+	return func(wt *Weighted) {
+		wt.stickyKey = fn
+	}
+}
+
+// WithStickyCookie returns a WeightedConfigFunc that makes requests with
+// the same value of the given cookie stick to the same target.
+func WithStickyCookie(name string) WeightedConfigFunc {
+	return WithStickyFunc(func(r *http.Request) string {
+		c, err := r.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return c.Value
+	})
+}
+
+// WithStickyHeader returns a WeightedConfigFunc that makes requests with
+// the same value of the given header stick to the same target.
+func WithStickyHeader(header string) WeightedConfigFunc {
+	return WithStickyFunc(func(r *http.Request) string {
+		return r.Header.Get(header)
+	})
+}
+
+// WithStickyIP returns a WeightedConfigFunc that makes requests from the
+// same client IP address stick to the same target.
+func WithStickyIP() WeightedConfigFunc {
+	return WithStickyFunc(func(r *http.Request) string {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return ""
+		}
+		return host
+	})
+}
+
+// SetWeight changes the weight of the named target.
+func (wt *Weighted) SetWeight(name string, weight int) error {
+	if weight < 0 {
+		return fmt.Errorf("splitter: negative weight %d for target %q", weight, name)
+	}
+
+	wt.mutex.Lock()
+	defer wt.mutex.Unlock()
+
+	t := wt.target(name)
+	if t == nil {
+		return fmt.Errorf("splitter: unknown target %q", name)
+	}
+	t.weight = weight
+	return nil
+}
+
+// Hits returns the number of requests the named target has received.
+func (wt *Weighted) Hits(name string) int64 {
+	wt.mutex.Lock()
+	defer wt.mutex.Unlock()
+
+	if t := wt.target(name); t != nil {
+		return t.hits
+	}
+	return 0
+}
+
+// ServeHTTP routes the request to one of the targets. If all weights are
+// zero the request is answered with http.StatusServiceUnavailable.
+func (wt *Weighted) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var key string
+	if wt.stickyKey != nil {
+		key = wt.stickyKey(r)
+	}
+
+	wt.mutex.Lock()
+	t := wt.pick(key)
+	if t != nil {
+		t.hits++
+	}
+	wt.mutex.Unlock()
+
+	if t == nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	t.handler.ServeHTTP(w, r)
+}
+
+// pick chooses the target for the key. Keys are assigned with weighted
+// rendezvous hashing, requests without a key are assigned randomly.
+func (wt *Weighted) pick(key string) *target {
+	if key != "" {
+		var (
+			best      *target
+			bestScore float64
+		)
+		for _, t := range wt.targets {
+			if t.weight == 0 {
+				continue
+			}
+			if score := rendezvousScore(key, t); best == nil || score > bestScore {
+				best, bestScore = t, score
+			}
+		}
+		return best
+	}
+
+	total := 0
+	for _, t := range wt.targets {
+		total += t.weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	n := rand.Intn(total)
+	for _, t := range wt.targets {
+		if n < t.weight {
+			return t
+		}
+		n -= t.weight
+	}
+	return nil
+}
+
+// rendezvousScore returns the score of the target for the key. The target
+// with the highest score gets the key.
+func rendezvousScore(key string, t *target) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(t.name))
+
+	// Mix the bits of the hash (the finalizer of splitmix64) and map it to a
+	// uniformly distributed number in (0, 1).
+	x := h.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+	u := (float64(x>>11) + 0.5) / (1 << 53)
+	return float64(t.weight) / -math.Log(u)
+}
+
+// target returns the named target, the mutex must be held if the Weighted
+// is serving requests.
+func (wt *Weighted) target(name string) *target {
+	for _, t := range wt.targets {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (wt *Weighted) setErr(err error) {
+	if wt.err == nil {
+		wt.err = err
+	}
+}
@@ -0,0 +1,133 @@
+package splitter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newWeighted(t *testing.T, hit *string, config ...WeightedConfigFunc) *Weighted {
+	t.Helper()
+
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*hit = name
+		})
+	}
+
+	config = append([]WeightedConfigFunc{
+		WithTarget("stable", 80, handler("stable")),
+		WithTarget("canary", 20, handler("canary")),
+	}, config...)
+
+	wt, err := NewWeighted(config...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wt
+}
+
+func TestWeightedDistribution(t *testing.T) {
+	hit := ""
+	wt := newWeighted(t, &hit)
+
+	for i := 0; i < 10000; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		wt.ServeHTTP(nil, req)
+	}
+
+	if wt.Hits("stable")+wt.Hits("canary") != 10000 {
+		t.Fatal("unexpected total hits:", wt.Hits("stable")+wt.Hits("canary"))
+	}
+
+	if canary := wt.Hits("canary"); canary < 1700 || canary > 2300 {
+		t.Fatal("unexpected canary hits:", canary)
+	}
+
+	if err := wt.SetWeight("stable", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	wt.ServeHTTP(nil, req)
+
+	if hit != "canary" {
+		t.Fatal("request sent to target with weight zero")
+	}
+
+	if err := wt.SetWeight("canary", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	wt.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatal("unexpected status code:", rec.Code)
+	}
+}
+
+func TestWeightedSticky(t *testing.T) {
+	hit := ""
+	wt := newWeighted(t, &hit, WithStickyHeader("X-User"))
+
+	assigned := make(map[string]string)
+	canaries := 0
+	for u := 0; u < 1000; u++ {
+		user := fmt.Sprint("user-", u)
+		for i := 0; i < 3; i++ {
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-User", user)
+			wt.ServeHTTP(nil, req)
+
+			if previous, found := assigned[user]; found && previous != hit {
+				t.Fatal("user flip-flopped between targets:", user)
+			}
+			assigned[user] = hit
+		}
+		if hit == "canary" {
+			canaries++
+		}
+	}
+
+	if canaries < 150 || canaries > 250 {
+		t.Fatal("unexpected number of canary users:", canaries)
+	}
+
+	if err := wt.SetWeight("canary", 50); err != nil {
+		t.Fatal(err)
+	}
+
+	for user, previous := range assigned {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User", user)
+		wt.ServeHTTP(nil, req)
+
+		if previous == "canary" && hit != "canary" {
+			t.Fatal("canary user moved away from canary when its weight increased:", user)
+		}
+	}
+}
+
+func TestInvalidWeighted(t *testing.T) {
+	empty := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := [][]WeightedConfigFunc{
+		{},
+		{WithTarget("a", 1, empty), WithTarget("a", 1, empty)},
+		{WithTarget("a", -1, empty)},
+	}
+
+	for _, config := range tests {
+		if _, err := NewWeighted(config...); err == nil {
+			t.Fatal("expected error for invalid targets")
+		}
+	}
+
+	hit := ""
+	wt := newWeighted(t, &hit)
+	if wt.SetWeight("unknown", 1) == nil || wt.SetWeight("stable", -1) == nil {
+		t.Fatal("expected error for invalid weight")
+	}
+}